@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+// Principal is a caller and the roles they hold.
+type Principal struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+// Anonymous holds no roles.
+var Anonymous = &Principal{Name: "anonymous"}
+
+// HasRole reports whether p holds any of roles.
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrUnauthenticated is returned for credentials that don't check out.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator identifies the caller of a request. Requests without
+// credentials are Anonymous.
+type Authenticator interface {
+	Authenticate(req *http.Request) (*Principal, error)
+}
+
+// Allowed reports whether p may see and run q.
+func Allowed(p *Principal, q compiler.QueryDoc) bool {
+	return len(q.Requires) == 0 || p.HasRole(q.Requires...)
+}
+
+// Redact clears the values of q's sensitive columns in rows unless p holds
+// one of reveal, returning the names of the columns it cleared.
+func Redact(p *Principal, q compiler.QueryDoc, rows []map[string]interface{}, reveal ...string) []string {
+	if p.HasRole(reveal...) {
+		return nil
+	}
+	redacted := make([]string, 0)
+	for _, c := range q.Output.Columns {
+		if !c.Sensitive {
+			continue
+		}
+		redacted = append(redacted, c.ProperName)
+		for _, row := range rows {
+			if _, ok := row[c.ProperName]; ok {
+				row[c.ProperName] = nil
+			}
+		}
+	}
+	return redacted
+}
+
+// StaticTokens authenticates bearer tokens against a fixed table, for
+// local use.
+type StaticTokens map[string]*Principal
+
+func (s StaticTokens) Authenticate(req *http.Request) (*Principal, error) {
+	header := req.Header.Get("Authorization")
+	if header == "" {
+		return Anonymous, nil
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	p, ok := s[strings.TrimSpace(token)]
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	return p, nil
+}
+
+// LoadStaticTokens reads a token file with one "token name role,role"
+// line per caller. Blank lines and lines starting with # are skipped.
+func LoadStaticTokens(path string) (StaticTokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tokens := make(StaticTokens)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: want \"token name [role,role]\"", path, n)
+		}
+		p := &Principal{Name: fields[1], Roles: make([]string, 0)}
+		if len(fields) == 3 {
+			for _, role := range strings.Split(fields[2], ",") {
+				if role != "" {
+					p.Roles = append(p.Roles, role)
+				}
+			}
+		}
+		tokens[fields[0]] = p
+	}
+	return tokens, scanner.Err()
+}
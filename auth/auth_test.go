@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var customers = compiler.QueryDoc{
+	Name:     "customers",
+	Requires: []string{"support", "admin"},
+	Output: compiler.Table{Columns: []compiler.Column{
+		{ProperName: "id"},
+		{ProperName: "email", Sensitive: true},
+	}},
+}
+
+func TestAllowed(t *testing.T) {
+	if Allowed(Anonymous, customers) {
+		t.Errorf("Anonymous shouldn't be allowed")
+	}
+	if !Allowed(&Principal{Roles: []string{"admin"}}, customers) {
+		t.Errorf("Admin should be allowed")
+	}
+	if !Allowed(Anonymous, compiler.QueryDoc{}) {
+		t.Errorf("Queries without @requires should be open")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	rows := []map[string]interface{}{{"id": 1, "email": "a@example.com"}}
+	redacted := Redact(&Principal{Roles: []string{"support"}}, customers, rows, "pii")
+	if len(redacted) != 1 || rows[0]["email"] != nil || rows[0]["id"] != 1 {
+		t.Errorf("Wrong redaction. Got %v and rows %v", redacted, rows)
+	}
+	rows = []map[string]interface{}{{"id": 1, "email": "a@example.com"}}
+	redacted = Redact(&Principal{Roles: []string{"pii"}}, customers, rows, "pii")
+	if len(redacted) != 0 || rows[0]["email"] != "a@example.com" {
+		t.Errorf("Wrong redaction. Got %v and rows %v", redacted, rows)
+	}
+}
+
+func TestStaticTokens(t *testing.T) {
+	tokens := StaticTokens{"s3cret": {Name: "ana", Roles: []string{"admin"}}}
+	req := httptest.NewRequest("GET", "/queries", nil)
+	p, err := tokens.Authenticate(req)
+	if err != nil || p != Anonymous {
+		t.Errorf("Wrong principal without a token. Got %v, %v", p, err)
+	}
+	req.Header.Set("Authorization", "Bearer s3cret")
+	p, err = tokens.Authenticate(req)
+	if err != nil || p.Name != "ana" {
+		t.Errorf("Wrong principal. Got %v, %v", p, err)
+	}
+	req.Header.Set("Authorization", "Bearer nope")
+	if _, err = tokens.Authenticate(req); err != ErrUnauthenticated {
+		t.Errorf("Wrong error. Got %v want %v", err, ErrUnauthenticated)
+	}
+}
@@ -0,0 +1,111 @@
+package bind
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Style is a driver's placeholder syntax.
+type Style int
+
+const (
+	// Question is ? placeholders, as used by SQLite and MySQL.
+	Question Style = iota
+	// Dollar is $1 style placeholders, as used by PostgreSQL.
+	Dollar
+)
+
+func ParseStyle(s string) (Style, error) {
+	switch s {
+	case "?", "question":
+		return Question, nil
+	case "$", "dollar":
+		return Dollar, nil
+	}
+	return 0, fmt.Errorf("unknown placeholder style %q", s)
+}
+
+// Rewrite replaces each ${name} reference in sql with a placeholder,
+// leaving literals, quoted identifiers and comments alone. It returns the
+// rewritten statement and the param name for each argument position. With
+// Dollar placeholders a repeated reference reuses its argument; with
+// Question placeholders it is passed again.
+func Rewrite(sql string, style Style) (string, []string, error) {
+	b := strings.Builder{}
+	names := make([]string, 0)
+	positions := make(map[string]int)
+	for i := 0; i < len(sql); i++ {
+		if n := quoted(sql[i:]); n > 0 {
+			b.WriteString(sql[i : i+n])
+			i += n - 1
+			continue
+		}
+		if !strings.HasPrefix(sql[i:], "${") {
+			b.WriteByte(sql[i])
+			continue
+		}
+		end := strings.IndexByte(sql[i:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("unterminated reference %q", sql[i:])
+		}
+		end += i
+		name := strings.TrimSpace(sql[i+2 : end])
+		if name == "" {
+			return "", nil, fmt.Errorf("empty reference at offset %d", i)
+		}
+		switch style {
+		case Dollar:
+			pos, ok := positions[name]
+			if !ok {
+				names = append(names, name)
+				pos = len(names)
+				positions[name] = pos
+			}
+			b.WriteString("$" + strconv.Itoa(pos))
+		default:
+			names = append(names, name)
+			b.WriteString("?")
+		}
+		i = end
+	}
+	return b.String(), names, nil
+}
+
+// quoted is the length of the literal, quoted identifier or comment sql
+// starts with, running to the end of sql if it's unterminated, or 0 if
+// sql starts with none.
+func quoted(sql string) int {
+	var close string
+	switch {
+	case strings.HasPrefix(sql, "'"), strings.HasPrefix(sql, `"`), strings.HasPrefix(sql, "`"):
+		close = sql[:1]
+	case strings.HasPrefix(sql, "--"):
+		close = "\n"
+	case strings.HasPrefix(sql, "/*"):
+		close = "*/"
+	default:
+		return 0
+	}
+	end := strings.Index(sql[len(close):], close)
+	if end < 0 {
+		return len(sql)
+	}
+	return len(close) + end + len(close)
+}
+
+// References lists each distinct param referenced by sql, in order of first
+// use.
+func References(sql string) []string {
+	_, names, _ := Rewrite(sql, Dollar)
+	return names
+}
+
+// StyleFor guesses the placeholder style of a database/sql driver.
+func StyleFor(driver string) Style {
+	switch driver {
+	case "postgres", "pgx", "pq", "cockroach":
+		return Dollar
+	}
+	return Question
+}
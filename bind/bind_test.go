@@ -0,0 +1,62 @@
+package bind
+
+import (
+	"reflect"
+	"testing"
+)
+
+const statement = "SELECT * FROM t WHERE a = ${a} AND b = ${ b } OR a2 = ${a}"
+
+func TestRewriteQuestion(t *testing.T) {
+	sql, names, err := Rewrite(statement, Question)
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if sql != "SELECT * FROM t WHERE a = ? AND b = ? OR a2 = ?" {
+		t.Errorf("Wrong statement. Got %v", sql)
+	}
+	if want := []string{"a", "b", "a"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Wrong names. Got %v want %v", names, want)
+	}
+}
+
+func TestRewriteDollar(t *testing.T) {
+	sql, names, err := Rewrite(statement, Dollar)
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if sql != "SELECT * FROM t WHERE a = $1 AND b = $2 OR a2 = $1" {
+		t.Errorf("Wrong statement. Got %v", sql)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Wrong names. Got %v want %v", names, want)
+	}
+}
+
+func TestRewriteUnterminated(t *testing.T) {
+	_, _, err := Rewrite("SELECT ${a", Question)
+	if err == nil {
+		t.Errorf("Expected an error for an unterminated reference")
+	}
+}
+
+func TestRewriteSkipsQuoted(t *testing.T) {
+	inputs := map[string]string{
+		"SELECT '${not_a_param}', ${a}":           "SELECT '${not_a_param}', ?",
+		"SELECT \"${col}\" FROM t WHERE a = ${a}": "SELECT \"${col}\" FROM t WHERE a = ?",
+		"SELECT ${a} -- ${x}\nFROM t":             "SELECT ? -- ${x}\nFROM t",
+		"SELECT /* ${x} */ ${a}":                  "SELECT /* ${x} */ ?",
+		"SELECT 'it''s ${x}' WHERE a = ${a}":      "SELECT 'it''s ${x}' WHERE a = ?",
+		"SELECT ${a} -- trailing ${x}":            "SELECT ? -- trailing ${x}",
+		"SELECT ${a} /* unterminated ${x}":        "SELECT ? /* unterminated ${x}",
+	}
+	for input, want := range inputs {
+		sql, names, err := Rewrite(input, Question)
+		if err != nil {
+			t.Fatalf("Unexpected err %v for %q", err, input)
+		}
+		if sql != want || !reflect.DeepEqual(names, []string{"a"}) {
+			t.Errorf("Wrong rewrite of %q. Got %q %v want %q [a]", input, sql, names, want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package catalog
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// Catalog is the set of documented queries across a source tree.
+type Catalog struct {
+	Docs []compiler.QueryDoc `json:"docs"`
+}
+
+// FormatVersion is the version of the JSON catalog format described by
+// Schema. Version 0 is the unversioned format before it: a bare array of
+// docs, or an object with just the docs.
+const FormatVersion = 1
+
+// Schema is the JSON Schema for the catalog format.
+//
+//go:embed catalog.schema.json
+var Schema []byte
+
+// Envelope is the JSON catalog format.
+type Envelope struct {
+	Version int                 `json:"version"`
+	Docs    []compiler.QueryDoc `json:"docs"`
+}
+
+// MarshalJSON writes the catalog in the current format.
+func (c Catalog) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Envelope{Version: FormatVersion, Docs: c.Docs})
+}
+
+func New(docs []compiler.QueryDoc) *Catalog {
+	if docs == nil {
+		docs = make([]compiler.QueryDoc, 0)
+	}
+	return &Catalog{Docs: docs}
+}
+
+// Compile tokenizes and compiles a single source a doc at a time.
+func Compile(r io.Reader) ([]compiler.QueryDoc, error) {
+	return CompileContext(context.Background(), r, tokenizer.Limits{})
+}
+
+// CompileContext is Compile, stopping once ctx is done or r goes past
+// limits. Docs that fail to compile are returned as far as they go, along
+// with the Diagnostics.
+func CompileContext(ctx context.Context, r io.Reader, limits tokenizer.Limits) ([]compiler.QueryDoc, error) {
+	docs := make([]compiler.QueryDoc, 0)
+	var diags compiler.Diagnostics
+	for doc, err := range compiler.NewStream(tokenizer.NewTokenizerContext(ctx, r, limits)).All() {
+		var d compiler.Diagnostics
+		switch {
+		case errors.As(err, &d):
+			diags = append(diags, d...)
+			if doc.Incomplete {
+				docs = append(docs, doc)
+			}
+		case err != nil:
+			return nil, err
+		default:
+			docs = append(docs, doc)
+		}
+	}
+	if len(diags) > 0 {
+		return docs, diags
+	}
+	return docs, nil
+}
+
+// Load compiles every path into a catalog. Directories are walked for .sql
+// files. Docs without a name are named after their file and position.
+func Load(paths ...string) (*Catalog, error) {
+	c := New(nil)
+	for _, path := range paths {
+		files, err := SQLFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			err := c.loadFile(file)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := c.Resolve(); err != nil {
+		return nil, err
+	}
+	return c, c.CheckNames()
+}
+
+// SQLFiles lists path, or the .sql files under it if it's a directory.
+func SQLFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files := make([]string, 0)
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".sql") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (c *Catalog) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	docs, err := Compile(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	c.Add(path, docs)
+	return nil
+}
+
+// Decode reads a catalog exported as JSON in any format version up to
+// FormatVersion, including a bare array of docs as served by /queries.
+func Decode(r io.Reader) (*Catalog, error) {
+	var raw json.RawMessage
+	err := json.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+	e := Envelope{Docs: make([]compiler.QueryDoc, 0)}
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		err = json.Unmarshal(raw, &e.Docs)
+	} else {
+		err = json.Unmarshal(raw, &e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Version 0 docs have the same shape as version 1's, which only added
+	// the version itself.
+	if e.Version < 0 || e.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported catalog format version %d, this build reads up to %d", e.Version, FormatVersion)
+	}
+	for i := range e.Docs {
+		e.Docs[i].Rehydrate()
+	}
+	return New(e.Docs), nil
+}
+
+// Open loads a JSON export, or compiles .sql files at paths.
+func Open(paths ...string) (*Catalog, error) {
+	if len(paths) == 1 && strings.EqualFold(filepath.Ext(paths[0]), ".json") {
+		f, err := os.Open(paths[0])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		c, err := Decode(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", paths[0], err)
+		}
+		return c, nil
+	}
+	return Load(paths...)
+}
+
+// Add appends docs compiled from source.
+func (c *Catalog) Add(source string, docs []compiler.QueryDoc) {
+	base := compiler.Slug(strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)))
+	for i, doc := range docs {
+		doc.Source = source
+		if doc.Name == "" {
+			doc.Name = fmt.Sprintf("%s_%d", base, i+1)
+		}
+		c.Docs = append(c.Docs, doc)
+	}
+}
+
+// Resolve expands the shared definitions in the catalog, dropping them.
+// See compiler.Resolve.
+func (c *Catalog) Resolve() error {
+	docs, err := compiler.Resolve(c.Docs)
+	c.Docs = docs
+	return err
+}
+
+// CheckNames fails on two docs with the same name.
+func (c *Catalog) CheckNames() error {
+	seen := make(map[string]string)
+	for _, doc := range c.Docs {
+		if other, ok := seen[doc.Name]; ok {
+			return fmt.Errorf("duplicate query name %q in %s and %s", doc.Name, other, doc.Source)
+		}
+		seen[doc.Name] = doc.Source
+	}
+	return nil
+}
+
+// Lookup finds a doc by name.
+func (c *Catalog) Lookup(name string) (compiler.QueryDoc, bool) {
+	for _, doc := range c.Docs {
+		if doc.Name == name {
+			return doc, true
+		}
+	}
+	return compiler.QueryDoc{}, false
+}
+
+// Filter selects docs by their governance annotations. Empty fields match
+// every doc.
+type Filter struct {
+	Owner      string
+	Team       string
+	Tag        string
+	Deprecated *bool
+}
+
+// Match reports whether doc passes the filter.
+func (f Filter) Match(doc compiler.QueryDoc) bool {
+	if f.Owner != "" && doc.Owner != f.Owner {
+		return false
+	}
+	if f.Team != "" && doc.Team != f.Team {
+		return false
+	}
+	if f.Tag != "" && !hasTag(doc, f.Tag) {
+		return false
+	}
+	if f.Deprecated != nil && *f.Deprecated != (doc.Deprecated != nil) {
+		return false
+	}
+	return true
+}
+
+func hasTag(doc compiler.QueryDoc, tag string) bool {
+	for _, t := range doc.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns a catalog of the docs matching f.
+func (c *Catalog) Filter(f Filter) *Catalog {
+	docs := make([]compiler.QueryDoc, 0)
+	for _, doc := range c.Docs {
+		if f.Match(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return New(docs)
+}
@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+func TestDecodeVersions(t *testing.T) {
+	inputs := map[string]string{
+		"bare array": `[{"name": "a", "sql": "SELECT 1"}]`,
+		"version 0":  `{"docs": [{"name": "a", "sql": "SELECT 1"}]}`,
+		"version 1":  `{"version": 1, "docs": [{"name": "a", "sql": "SELECT 1"}]}`,
+	}
+	for name, input := range inputs {
+		c, err := Decode(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if len(c.Docs) != 1 || c.Docs[0].Name != "a" {
+			t.Errorf("%v: wrong docs. Got %+v", name, c.Docs)
+		}
+	}
+	if _, err := Decode(strings.NewReader(`{"version": 2, "docs": []}`)); err == nil {
+		t.Error("Expected an error for a newer format version.")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	b, err := json.Marshal(New([]compiler.QueryDoc{{Name: "a", SQL: "SELECT 1"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte(`{"version":1,`)) {
+		t.Errorf("Wrong envelope. Got %s", b)
+	}
+	c, err := Decode(bytes.NewReader(b))
+	if err != nil || len(c.Docs) != 1 || c.Docs[0].SQL != "SELECT 1" {
+		t.Errorf("Wrong round trip. Got %+v, %v", c, err)
+	}
+}
+
+// TestSchemaFields catches fields added to the compiled tree but not to
+// the schema.
+func TestSchemaFields(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]interface{}{
+		"queryDoc":    compiler.QueryDoc{},
+		"param":       compiler.Param{},
+		"table":       compiler.Table{},
+		"column":      compiler.Column{},
+		"example":     compiler.Example{},
+		"deprecation": compiler.Deprecation{},
+		"policy":      compiler.Policy{},
+		"diagnostic":  compiler.Diagnostic{},
+	}
+	for def, v := range types {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if _, ok := schema.Defs[def].Properties[name]; !ok {
+				t.Errorf("The schema's %v is missing %v", def, name)
+			}
+		}
+	}
+}
+
+func TestCompilePartial(t *testing.T) {
+	src := `/**
+@title "Broken"
+@timeout soon
+*/
+SELECT 1;
+/**
+@title "Fine"
+*/
+SELECT 2;`
+	docs, err := Compile(strings.NewReader(src))
+	if len(compiler.AsDiagnostics(err)) != 1 {
+		t.Fatalf("Wrong diagnostics. Got %v want one", err)
+	}
+	if len(docs) != 2 || !docs[0].Incomplete || docs[0].Title != "Broken" || docs[1].Title != "Fine" {
+		t.Errorf("Incomplete doc wasn't kept. Got %+v", docs)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/diff"
+)
+
+func diffCmd(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print changes as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: diff [-json] old new\n\nold and new are .sql files, directories or JSON exports.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("diff needs an old and a new catalog")
+	}
+	old, err := catalog.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := catalog.Open(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	changes := diff.Catalogs(old, new)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(changes)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+	if diff.Breaking(changes) {
+		return errors.New("breaking changes")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/christopher-henderson/DocStringParser/bind"
+	"github.com/christopher-henderson/DocStringParser/codegen"
+)
+
+func genCmd(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	lang := flags.String("lang", "go", "language to generate: go or ts")
+	out := flags.String("o", "", "file to write, defaults to stdout")
+	pkg := flags.String("package", "queries", "package name of generated Go code")
+	placeholder := flags.String("placeholder", "?", "placeholder style of generated Go code: ? or $")
+	flags.Parse(args)
+	c, err := loadCatalog(flags.Args())
+	if err != nil {
+		return err
+	}
+	b := bytes.Buffer{}
+	switch *lang {
+	case "go":
+		style, err := bind.ParseStyle(*placeholder)
+		if err != nil {
+			return err
+		}
+		err = codegen.Go(&b, c.Docs, codegen.GoOptions{Package: *pkg, Placeholder: style})
+		if err != nil {
+			return err
+		}
+	case "ts", "typescript":
+		err = codegen.TypeScript(&b, c.Docs)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown language %q", *lang)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, b.Bytes(), 0644)
+}
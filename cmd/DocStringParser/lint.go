@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/christopher-henderson/DocStringParser/lint"
+)
+
+func lintCmd(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "fail when there are warnings")
+	flags.Parse(args)
+	c, err := loadCatalog(flags.Args())
+	if err != nil {
+		return err
+	}
+	warnings := lint.Catalog(c)
+	for _, w := range warnings {
+		fmt.Println(w)
+	}
+	if *strict && len(warnings) > 0 {
+		return fmt.Errorf("%d warnings", len(warnings))
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/christopher-henderson/DocStringParser/lsp"
+)
+
+func lspCmd(args []string) error {
+	return lsp.NewServer(os.Stdin, os.Stdout).Run()
+}
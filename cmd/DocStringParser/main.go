@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 
+	"github.com/christopher-henderson/DocStringParser/catalog"
 	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/format"
+	"github.com/christopher-henderson/DocStringParser/jsonschema"
 	"github.com/christopher-henderson/DocStringParser/tokenizer"
 )
 
@@ -90,28 +94,167 @@ func test() {
 	log.Println(string(j))
 }
 
-func compile(w http.ResponseWriter, req *http.Request) {
+// compileFailure is the body of a /compile or /schema response for a
+// request that didn't entirely compile.
+type compileFailure struct {
+	Version     int                  `json:"version"`
+	Error       string               `json:"error"`
+	Diagnostics compiler.Diagnostics `json:"diagnostics"`
+	Docs        []compiler.QueryDoc  `json:"docs"`
+}
+
+// compileLimits bound request bodies handed to /compile and /schema.
+var compileLimits = tokenizer.Limits{
+	MaxBytes:      1 << 20,
+	MaxDocs:       1000,
+	MaxTextLength: 64 << 10,
+	MaxNesting:    8,
+}
+
+// compileRequest compiles the request body. If anything fails to compile
+// it writes the diagnostics, along with whatever did compile, and returns
+// false. Input past compileLimits is a 413 and input that doesn't compile,
+// malformed or not, a 422. A body that can't be read is a 400 without
+// diagnostics, and a request canceled by its client gets no response.
+func compileRequest(w http.ResponseWriter, req *http.Request) ([]compiler.QueryDoc, bool) {
 	defer req.Body.Close()
-	tok := tokenizer.NewTokenizer(req.Body)
-	err := tok.Tokenize()
-	if err != nil {
-		log.Panic(err)
+	tok := tokenizer.NewTokenizerContext(req.Context(), req.Body, compileLimits)
+	tokErr := tok.Tokenize()
+	tree, err := compiler.CompileContext(req.Context(), tok.Tokens())
+	tree, resolveErr := compiler.Resolve(tree)
+	if tokErr == nil && err == nil && resolveErr == nil {
+		return tree, true
+	}
+	var syntax *tokenizer.Error
+	switch {
+	case req.Context().Err() != nil:
+		log.Printf("compile request canceled: %v", req.Context().Err())
+		return nil, false
+	case tokErr != nil && !errors.As(tokErr, &syntax):
+		writeJSON(w, http.StatusBadRequest, "application/json", errorBody{Error: "reading input failed: " + tokErr.Error()})
+		return nil, false
+	}
+	failure := compileFailure{Version: catalog.FormatVersion, Diagnostics: make(compiler.Diagnostics, 0), Docs: tree}
+	status := http.StatusUnprocessableEntity
+	failure.Error = "compile failed"
+	switch {
+	case errors.Is(tokErr, tokenizer.ErrLimit):
+		// Whatever compiled is only the part of the input read so far.
+		status = http.StatusRequestEntityTooLarge
+		failure.Error = "input too large"
+		failure.Docs = make([]compiler.QueryDoc, 0)
+		err, resolveErr = nil, nil
+	case tokErr != nil:
+		status = http.StatusBadRequest
+		failure.Error = "malformed input"
+	}
+	if tokErr != nil {
+		failure.Diagnostics = append(failure.Diagnostics, compiler.AsDiagnostics(tokErr)...)
 	}
-	tree, err := compiler.Compile(tok.Tokens())
 	if err != nil {
-		log.Panic(err)
+		failure.Diagnostics = append(failure.Diagnostics, compiler.AsDiagnostics(err)...)
 	}
-	j, err := json.Marshal(tree)
+	if resolveErr != nil {
+		failure.Diagnostics = append(failure.Diagnostics, compiler.AsDiagnostics(resolveErr)...)
+	}
+	writeJSON(w, status, "application/json", failure)
+	return nil, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, v interface{}) {
+	j, err := json.Marshal(v)
 	if err != nil {
-		log.Panic(err)
+		log.Println(err)
+		http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+		return
 	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
 	w.Write(j)
 }
 
+// encoderFor picks the encoder named by the format query parameter, or
+// else the one for the Accept header. If there's none it writes a 400 or
+// 406 and returns false.
+func encoderFor(w http.ResponseWriter, req *http.Request) (format.Encoder, bool) {
+	if name := req.URL.Query().Get("format"); name != "" {
+		enc, ok := format.Lookup(name)
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, "application/json", errorBody{Error: "unknown format " + name})
+		}
+		return enc, ok
+	}
+	enc, ok := format.Negotiate(req.Header.Get("Accept"))
+	if !ok {
+		writeJSON(w, http.StatusNotAcceptable, "application/json", errorBody{Error: "acceptable formats are " + strings.Join(format.Names(), ", ")})
+	}
+	return enc, ok
+}
+
+func writeDocs(w http.ResponseWriter, enc format.Encoder, docs []compiler.QueryDoc) {
+	b := bytes.Buffer{}
+	if err := enc.Encode(&b, docs); err != nil {
+		log.Println(err)
+		http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.Write(b.Bytes())
+}
+
+func compile(w http.ResponseWriter, req *http.Request) {
+	enc, ok := encoderFor(w, req)
+	if !ok {
+		return
+	}
+	tree, ok := compileRequest(w, req)
+	if !ok {
+		return
+	}
+	writeDocs(w, enc, tree)
+}
+
+// catalogSchema serves the JSON Schema for /compile responses.
+func catalogSchema(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(catalog.Schema)
+}
+
+func schema(w http.ResponseWriter, req *http.Request) {
+	tree, ok := compileRequest(w, req)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, "application/schema+json", jsonschema.ForQueries(tree))
+}
+
 func main() {
-	http.HandleFunc("/compile", compile)
-	log.Println("Starting in server mode.")
-	port := fmt.Sprintf(":%v", os.Getenv("PORT"))
-	log.Printf("Listening on port %v\n", port)
-	log.Fatal(http.ListenAndServe(":1337", nil))
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "serve":
+			err = serveCmd(os.Args[2:])
+		case "render":
+			err = renderCmd(os.Args[2:])
+		case "gen":
+			err = genCmd(os.Args[2:])
+		case "lsp":
+			err = lspCmd(os.Args[2:])
+		case "verify":
+			err = verifyCmd(os.Args[2:])
+		case "test":
+			err = testCmd(os.Args[2:])
+		case "lint":
+			err = lintCmd(os.Args[2:])
+		case "diff":
+			err = diffCmd(os.Args[2:])
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	log.Fatal(serveCmd(nil))
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// failingReader fails after the text it holds.
+type failingReader struct {
+	r io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   io.Reader
+		status int
+		error  string
+	}{
+		{"compiles", strings.NewReader("/**\n@name q\n*/\nSELECT 1;"), http.StatusOK, ""},
+		{"doesn't compile", strings.NewReader("/**\n@name q\n@param x wat \"X\" \"Y\"\n*/\nSELECT 1;"), http.StatusUnprocessableEntity, "compile failed"},
+		{"doesn't resolve", strings.NewReader("/**\n@name q\n@include nothing\n*/\nSELECT 1;"), http.StatusUnprocessableEntity, "compile failed"},
+		{"too large", strings.NewReader("/**\n@name q\n@title \"" + strings.Repeat("x", compileLimits.MaxBytes) + "\"\n*/"), http.StatusRequestEntityTooLarge, "input too large"},
+		{"unreadable", failingReader{strings.NewReader("/**\n@name q\n")}, http.StatusBadRequest, "reading input failed: connection reset"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		compile(w, httptest.NewRequest("POST", "/compile", c.body))
+		if w.Code != c.status {
+			t.Errorf("%v: wrong status. Got %v want %v: %v", c.name, w.Code, c.status, w.Body.String())
+			continue
+		}
+		if c.error == "" {
+			continue
+		}
+		var body struct {
+			Error       string            `json:"error"`
+			Diagnostics []json.RawMessage `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Error != c.error {
+			t.Errorf("%v: wrong error. Got %q want %q", c.name, body.Error, c.error)
+		}
+		if c.status == http.StatusUnprocessableEntity && len(body.Diagnostics) == 0 {
+			t.Errorf("%v: expected diagnostics. Got %v", c.name, w.Body.String())
+		}
+	}
+}
+
+func TestCompileCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	compile(w, httptest.NewRequest("POST", "/compile", strings.NewReader(strings.Repeat("SELECT 1;\n", 1000))).WithContext(ctx))
+	if w.Body.Len() > 0 {
+		t.Errorf("Expected no response to a canceled request. Got %v %v", w.Code, w.Body.String())
+	}
+}
+
+func TestCompileFormats(t *testing.T) {
+	cases := map[string]struct {
+		status      int
+		contentType string
+	}{
+		"application/yaml":     {http.StatusOK, "application/yaml"},
+		"application/toml;q=0": {http.StatusNotAcceptable, "application/json"},
+		"":                     {http.StatusOK, "application/json"},
+	}
+	for accept, want := range cases {
+		req := httptest.NewRequest("POST", "/compile", strings.NewReader("/**\n@name q\n*/\nSELECT 1;"))
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		compile(w, req)
+		if w.Code != want.status || w.Header().Get("Content-Type") != want.contentType {
+			t.Errorf("Wrong response for Accept %q. Got %v %v want %v %v", accept, w.Code, w.Header().Get("Content-Type"), want.status, want.contentType)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/format"
+	"github.com/christopher-henderson/DocStringParser/jsonschema"
+	"github.com/christopher-henderson/DocStringParser/openapi"
+	"github.com/christopher-henderson/DocStringParser/project"
+	"github.com/christopher-henderson/DocStringParser/render"
+	"github.com/christopher-henderson/DocStringParser/watch"
+)
+
+func renderCmd(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	name := flags.String("format", "html", "output format: html, markdown, jsonschema, openapi or one of "+strings.Join(format.Names(), ", "))
+	paramsIn := flags.String("params", openapi.InBody, "where openapi operations take params: body or query")
+	out := flags.String("o", "", "file to write, defaults to stdout")
+	watching := flags.Bool("watch", false, "re-render whenever the sources change, needs -o or -addr")
+	addr := flags.String("addr", "", "with -watch, serve the rendering here and reload it in the browser")
+	interval := flags.Duration("interval", time.Second, "how often -watch polls for changes")
+	filter := filterFlags(flags)
+	flags.Parse(args)
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+	r := renderer{format: *name, paramsIn: *paramsIn, filter: f}
+	if *watching {
+		if *out == "" && *addr == "" {
+			return errors.New("render -watch needs -o or -addr")
+		}
+		return r.watch(flags.Args(), *out, *addr, *interval)
+	}
+	c, err := loadCatalog(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return r.render(os.Stdout, c)
+	}
+	return r.renderFile(*out, c)
+}
+
+type renderer struct {
+	format   string
+	paramsIn string
+	filter   catalog.Filter
+}
+
+func (r renderer) render(w io.Writer, c *catalog.Catalog) error {
+	docs := c.Filter(r.filter).Docs
+	switch r.format {
+	case "html":
+		return render.HTML(w, docs)
+	case "markdown", "md":
+		return render.Markdown(w, docs)
+	case "jsonschema":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonschema.ForQueries(docs))
+	case "openapi":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(openapi.Generate(docs, openapi.Options{ParamsIn: r.paramsIn}))
+	default:
+		enc, ok := format.Lookup(r.format)
+		if !ok {
+			return fmt.Errorf("unknown render format %q", r.format)
+		}
+		return enc.Encode(w, docs)
+	}
+}
+
+func (r renderer) renderFile(path string, c *catalog.Catalog) error {
+	b := bytes.Buffer{}
+	err := r.render(&b, c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// watch re-renders into out and serves the latest rendering on addr,
+// either of which may be empty.
+func (r renderer) watch(paths []string, out, addr string, interval time.Duration) error {
+	tree, err := watch.New(paths...)
+	if err != nil {
+		log.Println(err)
+	}
+	events := watch.NewBroker()
+	update := func(c *catalog.Catalog) {
+		if out != "" {
+			if err := r.renderFile(out, c); err != nil {
+				log.Println(err)
+				return
+			}
+			log.Printf("Wrote %v.", out)
+		}
+		events.Publish("reload")
+	}
+	update(tree.Catalog())
+	if addr == "" {
+		tree.Watch(context.Background(), interval, update, func(err error) { log.Println(err) })
+		return nil
+	}
+	go tree.Watch(context.Background(), interval, update, func(err error) { log.Println(err) })
+	mux := http.NewServeMux()
+	mux.Handle("GET /events", events)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, req *http.Request) {
+		switch r.format {
+		case "html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "markdown", "md":
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			if enc, ok := format.Lookup(r.format); ok {
+				w.Header().Set("Content-Type", enc.ContentType())
+			}
+		}
+		err := r.render(w, tree.Catalog())
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if r.format == "html" {
+			io.WriteString(w, watch.ReloadScript)
+		}
+	})
+	log.Printf("Serving the rendering on %v\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// filterFlags registers catalog filter flags, returning a func to build
+// the filter once the flags are parsed.
+func filterFlags(flags *flag.FlagSet) func() (catalog.Filter, error) {
+	owner := flags.String("owner", "", "only queries with this @owner")
+	team := flags.String("team", "", "only queries with this @team")
+	tag := flags.String("tag", "", "only queries with this tag")
+	deprecated := flags.String("deprecated", "", "true for only deprecated queries, false to leave them out")
+	return func() (catalog.Filter, error) {
+		return newFilter(*owner, *team, *tag, *deprecated)
+	}
+}
+
+func newFilter(owner, team, tag, deprecated string) (catalog.Filter, error) {
+	f := catalog.Filter{Owner: owner, Team: team, Tag: tag}
+	if deprecated != "" {
+		d, err := strconv.ParseBool(deprecated)
+		if err != nil {
+			return f, fmt.Errorf("deprecated must be true or false, got %q", deprecated)
+		}
+		f.Deprecated = &d
+	}
+	return f, nil
+}
+
+// loadCatalog loads every path, or stdin if there are none.
+func loadCatalog(paths []string) (*catalog.Catalog, error) {
+	if len(paths) == 0 {
+		docs, err := catalog.Compile(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		c := catalog.New(nil)
+		c.Add("stdin", docs)
+		return c, c.Resolve()
+	}
+	return project.New(project.Options{CacheDir: cacheDir()}).Compile(paths...)
+}
+
+// cacheDir is where compiled docs are cached: $DOCSTRINGPARSER_CACHE, or
+// off to disable caching, defaulting to the user cache directory.
+func cacheDir() string {
+	dir := os.Getenv("DOCSTRINGPARSER_CACHE")
+	switch dir {
+	case "off":
+		return ""
+	case "":
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(base, "DocStringParser")
+	}
+	return dir
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/christopher-henderson/DocStringParser/auth"
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/format"
+	"github.com/christopher-henderson/DocStringParser/render"
+	"github.com/christopher-henderson/DocStringParser/runner"
+	"github.com/christopher-henderson/DocStringParser/watch"
+
+	_ "modernc.org/sqlite"
+)
+
+type server struct {
+	// catalog returns the current catalog, which changes under -watch.
+	catalog func() *catalog.Catalog
+	runner  *runner.Runner
+	auth    auth.Authenticator
+	// reveal are the roles that see sensitive columns.
+	reveal []string
+	// events pushes reloads to open doc pages under -watch.
+	events *watch.Broker
+}
+
+func serveCmd(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":1337", "address to listen on, overridden by $PORT")
+	driver := flags.String("driver", "sqlite", "database/sql driver of -dsn")
+	dsn := flags.String("dsn", "", "database to run queries against, running is disabled without one")
+	tokens := flags.String("tokens", "", `file of "token name role,role" lines to authenticate bearer tokens with`)
+	reveal := flags.String("reveal", "pii", "comma separated roles that see @sensitive columns")
+	watching := flags.Bool("watch", false, "recompile changed files and reload open doc pages")
+	interval := flags.Duration("interval", time.Second, "how often -watch polls for changes")
+	flags.IntVar(&compileLimits.MaxBytes, "max-bytes", compileLimits.MaxBytes, "largest body /compile accepts")
+	flags.IntVar(&compileLimits.MaxDocs, "max-docs", compileLimits.MaxDocs, "most docs /compile accepts, 0 for no limit")
+	flags.IntVar(&compileLimits.MaxTextLength, "max-text", compileLimits.MaxTextLength, "longest string /compile accepts, 0 for no limit")
+	flags.IntVar(&compileLimits.MaxNesting, "max-nesting", compileLimits.MaxNesting, "deepest { } nesting /compile accepts, 0 for no limit")
+	flags.Parse(args)
+	if port := os.Getenv("PORT"); port != "" {
+		*addr = ":" + port
+	}
+	empty := catalog.New(nil)
+	s := &server{catalog: func() *catalog.Catalog { return empty }, auth: auth.StaticTokens{}, reveal: strings.Split(*reveal, ",")}
+	if *tokens != "" {
+		t, err := auth.LoadStaticTokens(*tokens)
+		if err != nil {
+			return err
+		}
+		s.auth = t
+	}
+	switch {
+	case *watching:
+		tree, err := watch.New(flags.Args()...)
+		if err != nil {
+			log.Println(err)
+		}
+		s.catalog, s.events = tree.Catalog, watch.NewBroker()
+		go tree.Watch(context.Background(), *interval, func(c *catalog.Catalog) {
+			log.Printf("Recompiled, %d queries.", len(c.Docs))
+			s.events.Publish("reload")
+		}, func(err error) {
+			log.Println(err)
+		})
+	case flags.NArg() > 0:
+		c, err := loadCatalog(flags.Args())
+		if err != nil {
+			return err
+		}
+		s.catalog = func() *catalog.Catalog { return c }
+	}
+	if *dsn != "" {
+		db, err := sql.Open(*driver, *dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		s.runner = runner.New(db, *driver)
+	}
+	log.Println("Starting in server mode.")
+	log.Printf("Listening on %v\n", *addr)
+	return http.ListenAndServe(*addr, s.routes())
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compile", compile)
+	mux.HandleFunc("/schema", schema)
+	mux.HandleFunc("GET /catalog.schema.json", catalogSchema)
+	mux.HandleFunc("GET /queries", s.list)
+	mux.HandleFunc("POST /queries/{name}/run", s.run)
+	mux.HandleFunc("GET /queries/{name}/run", s.run)
+	mux.HandleFunc("GET /{$}", s.docs)
+	if s.events != nil {
+		mux.Handle("GET /events", s.events)
+	}
+	return mux
+}
+
+type errorBody struct {
+	Error    string           `json:"error"`
+	Problems []runner.Problem `json:"problems,omitempty"`
+}
+
+// principal authenticates req, answering it with 401 on failure.
+func (s *server) principal(w http.ResponseWriter, req *http.Request) (*auth.Principal, bool) {
+	p, err := s.auth.Authenticate(req)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, "application/json", errorBody{Error: err.Error()})
+		return nil, false
+	}
+	return p, true
+}
+
+// docs renders the docs the caller may see as a page.
+func (s *server) docs(w http.ResponseWriter, req *http.Request) {
+	p, ok := s.principal(w, req)
+	if !ok {
+		return
+	}
+	docs := make([]compiler.QueryDoc, 0)
+	for _, q := range s.catalog().Docs {
+		if auth.Allowed(p, q) {
+			docs = append(docs, q)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := render.HTML(w, docs)
+	if err != nil {
+		log.Printf("rendering docs: %v", err)
+		return
+	}
+	if s.events != nil {
+		io.WriteString(w, watch.ReloadScript)
+	}
+}
+
+func (s *server) list(w http.ResponseWriter, req *http.Request) {
+	p, ok := s.principal(w, req)
+	if !ok {
+		return
+	}
+	enc, ok := encoderFor(w, req)
+	if !ok {
+		return
+	}
+	query := req.URL.Query()
+	f, err := newFilter(query.Get("owner"), query.Get("team"), query.Get("tag"), query.Get("deprecated"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, "application/json", errorBody{Error: err.Error()})
+		return
+	}
+	docs := make([]compiler.QueryDoc, 0)
+	for _, q := range s.catalog().Filter(f).Docs {
+		if auth.Allowed(p, q) {
+			docs = append(docs, q)
+		}
+	}
+	// JSON stays the bare array it always was.
+	if _, ok := enc.(format.JSON); ok {
+		writeJSON(w, http.StatusOK, "application/json", docs)
+		return
+	}
+	writeDocs(w, enc, docs)
+}
+
+func (s *server) run(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	p, ok := s.principal(w, req)
+	if !ok {
+		return
+	}
+	q, ok := s.catalog().Lookup(req.PathValue("name"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, "application/json", errorBody{Error: "no such query"})
+		return
+	}
+	if !auth.Allowed(p, q) {
+		writeJSON(w, http.StatusForbidden, "application/json", errorBody{Error: "requires one of the roles " + strings.Join(q.Requires, ", ")})
+		return
+	}
+	// A GET can be made by a link or a prefetch, so it may only read.
+	if req.Method == http.MethodGet && !q.Policy.ReadOnly {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, "application/json", errorBody{Error: "only @readonly queries run on GET"})
+		return
+	}
+	if s.runner == nil {
+		writeJSON(w, http.StatusServiceUnavailable, "application/json", errorBody{Error: "no database configured"})
+		return
+	}
+	values, err := paramValues(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, "application/json", errorBody{Error: err.Error()})
+		return
+	}
+	result, err := s.runner.Run(req.Context(), q, values)
+	var invalid *runner.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusBadRequest, "application/json", errorBody{Error: "invalid params", Problems: invalid.Problems})
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, "application/json", errorBody{Error: fmt.Sprintf("query took longer than %v", q.Policy.Timeout)})
+	case err != nil:
+		log.Printf("running %v: %v", q.Name, err)
+		writeJSON(w, http.StatusInternalServerError, "application/json", errorBody{Error: err.Error()})
+	default:
+		result = redact(p, q, result, s.reveal)
+		if q.Policy.Cache > 0 && len(result.Redacted) == 0 && len(q.Requires) == 0 {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(q.Policy.Cache.Seconds())))
+		}
+		writeJSON(w, http.StatusOK, "application/json", result)
+	}
+}
+
+// paramValues reads the param values of a run: a JSON object body on
+// POST, query parameters on GET of a @readonly query.
+func paramValues(req *http.Request) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if req.Method == http.MethodGet {
+		for name, given := range req.URL.Query() {
+			if len(given) > 1 {
+				return nil, fmt.Errorf("param %v is given more than once", name)
+			}
+			values[name] = given[0]
+		}
+		return values, nil
+	}
+	dec := json.NewDecoder(req.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil && err != io.EOF {
+		return nil, errors.New("body must be a JSON object of param values")
+	}
+	return values, nil
+}
+
+// redact copies result with sensitive columns withheld from p, leaving
+// the runner's cached result alone.
+func redact(p *auth.Principal, q compiler.QueryDoc, result *runner.Result, reveal []string) *runner.Result {
+	copied := *result
+	copied.Rows = make([]map[string]interface{}, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		r := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			r[k] = v
+		}
+		copied.Rows = append(copied.Rows, r)
+	}
+	copied.Redacted = auth.Redact(p, q, copied.Rows, reveal...)
+	return &copied
+}
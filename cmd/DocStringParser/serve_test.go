@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/auth"
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/runner"
+)
+
+var queries = []compiler.QueryDoc{{
+	Name:   "open_orders",
+	Params: []compiler.Param{{ProperName: "status", Type: compiler.String, Required: true}},
+	Output: compiler.Table{Columns: []compiler.Column{
+		{ProperName: "id", Type: compiler.Integer},
+		{ProperName: "email", Type: compiler.String, Sensitive: true},
+	}},
+	SQL:    "SELECT id, email FROM orders WHERE status = ${status} ORDER BY id",
+	Policy: compiler.Policy{ReadOnly: true},
+}, {
+	Name:   "close_orders",
+	SQL:    "UPDATE orders SET status = 'closed'",
+	Output: compiler.NewTable(),
+}, {
+	Name:     "audit",
+	SQL:      "SELECT 1 AS one",
+	Output:   compiler.NewTable(),
+	Requires: []string{"auditor"},
+	Policy:   compiler.Policy{ReadOnly: true},
+}}
+
+// testServer serves queries against an in-memory database of orders.
+func testServer(t *testing.T) *httptest.Server {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE orders (id INTEGER, email TEXT, status TEXT);
+INSERT INTO orders VALUES (1, 'a@example.com', 'open'), (2, 'b@example.com', 'closed');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := catalog.New(queries)
+	s := &server{
+		catalog: func() *catalog.Catalog { return c },
+		runner:  runner.New(db, "sqlite"),
+		auth: auth.StaticTokens{
+			"admin":  {Name: "admin", Roles: []string{"pii", "auditor"}},
+			"nobody": {Name: "nobody", Roles: []string{}},
+		},
+		reveal: []string{"pii"},
+	}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, method, url, token, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b := strings.Builder{}
+	_, err = io.Copy(&b, resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, b.String()
+}
+
+func TestRunGet(t *testing.T) {
+	ts := testServer(t)
+	resp, body := do(t, "GET", ts.URL+"/queries/open_orders/run?status=open", "admin", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"rows":[{"email":"a@example.com","id":1}]`) {
+		t.Errorf("Wrong GET of a @readonly query. Got %v %v", resp.StatusCode, body)
+	}
+	resp, body = do(t, "GET", ts.URL+"/queries/close_orders/run", "", "")
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "POST" {
+		t.Errorf("Expected 405 for GET of a query that may write. Got %v %v", resp.StatusCode, body)
+	}
+	_, body = do(t, "GET", ts.URL+"/queries/open_orders/run?status=closed", "admin", "")
+	if !strings.Contains(body, `"id":2`) {
+		t.Errorf("The refused GET still wrote. Got %v", body)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ts := testServer(t)
+	cases := []struct {
+		name, method, path, token, body string
+		status                          int
+		want                            string
+	}{
+		{"revealed", "POST", "/queries/open_orders/run", "admin", `{"status":"open"}`, http.StatusOK, `"rows":[{"email":"a@example.com","id":1}]`},
+		{"redacted", "POST", "/queries/open_orders/run", "nobody", `{"status":"open"}`, http.StatusOK, `"rows":[{"email":null,"id":1}],"rowCount":1,"redacted":["email"]`},
+		{"anonymous redacted", "POST", "/queries/open_orders/run", "", `{"status":"open"}`, http.StatusOK, `"email":null`},
+		{"bad token", "POST", "/queries/open_orders/run", "forged", `{}`, http.StatusUnauthorized, `"error":"unauthenticated"`},
+		{"missing role", "POST", "/queries/audit/run", "nobody", `{}`, http.StatusForbidden, `requires one of the roles auditor`},
+		{"role", "POST", "/queries/audit/run", "admin", `{}`, http.StatusOK, `"rows":[{"one":1}]`},
+		{"unknown", "POST", "/queries/nothing/run", "", `{}`, http.StatusNotFound, `no such query`},
+		{"invalid", "POST", "/queries/open_orders/run", "", `{"color":"red"}`, http.StatusBadRequest, `"problems":[{"param":"status","message":"is required"},{"param":"color","message":"is not a param of open_orders"}]`},
+		{"not an object", "POST", "/queries/open_orders/run", "", `[1]`, http.StatusBadRequest, `body must be a JSON object`},
+		{"repeated", "GET", "/queries/open_orders/run?status=a&status=b", "", "", http.StatusBadRequest, `param status is given more than once`},
+	}
+	for _, c := range cases {
+		resp, body := do(t, c.method, ts.URL+c.path, c.token, c.body)
+		if resp.StatusCode != c.status || !strings.Contains(body, c.want) {
+			t.Errorf("%v: wrong response. Got %v %v want %v containing %v", c.name, resp.StatusCode, body, c.status, c.want)
+		}
+	}
+	resp, _ := do(t, "POST", ts.URL+"/queries/open_orders/run", "forged", "{}")
+	if resp.Header.Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("Wrong WWW-Authenticate. Got %q", resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+func TestList(t *testing.T) {
+	ts := testServer(t)
+	cases := []struct {
+		name, path, accept, token string
+		status                    int
+		contentType, want         string
+	}{
+		{"json", "/queries", "", "", http.StatusOK, "application/json", `"name":"open_orders"`},
+		{"yaml", "/queries", "application/yaml", "", http.StatusOK, "application/yaml", `name: "open_orders"`},
+		{"preferred", "/queries", "application/yaml;q=0.5, application/toml", "", http.StatusOK, "application/toml", `name = "open_orders"`},
+		{"format", "/queries?format=toml", "application/yaml", "", http.StatusOK, "application/toml", `name = "open_orders"`},
+		{"unacceptable", "/queries", "text/html, application/json;q=0", "", http.StatusNotAcceptable, "application/json", `acceptable formats are`},
+		{"unknown format", "/queries?format=xml", "", "", http.StatusBadRequest, "application/json", `unknown format xml`},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", ts.URL+c.path, nil)
+		req.Header.Set("Accept", c.accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.status || resp.Header.Get("Content-Type") != c.contentType || !strings.Contains(string(b), c.want) {
+			t.Errorf("%v: wrong response. Got %v %v %s want %v %v containing %v", c.name, resp.StatusCode, resp.Header.Get("Content-Type"), b, c.status, c.contentType, c.want)
+		}
+	}
+	_, body := do(t, "GET", ts.URL+"/queries", "", "")
+	if strings.Contains(body, `"audit"`) {
+		t.Errorf("A query the caller may not see was listed. Got %v", body)
+	}
+	_, body = do(t, "GET", ts.URL+"/queries", "admin", "")
+	if !strings.Contains(body, `"audit"`) {
+		t.Errorf("A query the caller may see wasn't listed. Got %v", body)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/examples"
+	"github.com/christopher-henderson/DocStringParser/runner"
+)
+
+type seeds []string
+
+func (s *seeds) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *seeds) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func testCmd(args []string) error {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	driver := flags.String("driver", "sqlite", "database/sql driver of -db")
+	dsn := flags.String("db", ":memory:", "database to run examples against")
+	fixtures := seeds{}
+	flags.Var(&fixtures, "seed", "a .sql script or table.csv to load before running; repeatable")
+	flags.Parse(args)
+	c, err := loadCatalog(flags.Args())
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// An in-memory database only lives as long as its connection.
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = examples.Seed(ctx, tx, fixtures...)
+	if err != nil {
+		return err
+	}
+	r := runner.New(tx, *driver)
+	total, failed := 0, 0
+	for _, q := range c.Docs {
+		for _, ex := range q.Examples {
+			total++
+			failures, err := runExample(ctx, tx, r, q, ex)
+			if err != nil {
+				fmt.Printf("%s: %s: example %q: %v\n", q.Source, q.Name, ex.Name, err)
+				failed++
+				continue
+			}
+			for _, f := range failures {
+				fmt.Printf("%s: %v\n", q.Source, f)
+			}
+			if len(failures) > 0 {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d examples failed", failed, total)
+	}
+	fmt.Printf("%d examples passed\n", total)
+	return nil
+}
+
+// runExample runs an example inside a savepoint it rolls back to
+// afterwards, so one example's writes, or a failed statement aborting the
+// transaction, don't carry over to the next.
+func runExample(ctx context.Context, tx *sql.Tx, r *runner.Runner, q compiler.QueryDoc, ex compiler.Example) ([]examples.Failure, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT example"); err != nil {
+		return nil, err
+	}
+	failures, err := examples.Run(ctx, r, q, ex)
+	if _, rerr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT example"); rerr != nil && err == nil {
+		err = rerr
+	}
+	if _, rerr := tx.ExecContext(ctx, "RELEASE SAVEPOINT example"); rerr != nil && err == nil {
+		err = rerr
+	}
+	return failures, err
+}
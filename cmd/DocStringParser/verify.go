@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/christopher-henderson/DocStringParser/runner"
+	"github.com/christopher-henderson/DocStringParser/verify"
+)
+
+func verifyCmd(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	driver := flags.String("driver", "sqlite", "database/sql driver of -db")
+	dsn := flags.String("db", "", "fixture database to run queries against")
+	flags.Parse(args)
+	if *dsn == "" {
+		return errors.New("verify needs a -db to run against")
+	}
+	c, err := loadCatalog(flags.Args())
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// Roll back whatever the queries do so the fixture stays put.
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	r := runner.New(tx, *driver)
+	failed := 0
+	for _, q := range c.Docs {
+		if len(q.Output.Columns) == 0 {
+			continue
+		}
+		mismatches, err := verify.Query(context.Background(), r, q, nil)
+		if err != nil {
+			fmt.Printf("%s: %s: %v\n", q.Source, q.Name, err)
+			failed++
+			continue
+		}
+		for _, m := range mismatches {
+			fmt.Printf("%s: %v\n", q.Source, m)
+		}
+		if len(mismatches) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queries don't match their docs", failed, len(c.Docs))
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/christopher-henderson/DocStringParser/bind"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+type GoOptions struct {
+	Package     string
+	Placeholder bind.Style
+}
+
+type goQuery struct {
+	Func        string
+	Const       string
+	Comment     []string
+	SQL         string
+	Params      []goField
+	Args        []string
+	Row         string
+	Columns     []goField
+	HasParams   bool
+	HasColumns  bool
+	ParamStruct string
+}
+
+type goField struct {
+	Name string
+	// Var is the local holding an optional param's value, prefixed so it
+	// can't clash with the function's own locals or a Go keyword.
+	Var     string
+	Type    string
+	Comment []string
+	// Optional fields are pointers, Default is the Go literal used when
+	// they're nil.
+	Optional bool
+	Default  string
+	// Used is set if the statement references the field.
+	Used bool
+}
+
+var goTemplate = template.Must(template.New("go").Parse(`// Code generated by DocStringParser. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"database/sql"
+{{- if .Math}}
+	"math"
+{{- end}}
+{{- if .Time}}
+	"time"
+{{- end}}
+)
+
+// DBTX is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+{{range .Queries}}
+const {{.Const}} = {{.SQL}}
+{{if .HasParams}}
+// {{.ParamStruct}} are the parameters of {{.Func}}.
+type {{.ParamStruct}} struct {
+{{- range .Params}}
+{{- range .Comment}}
+	//{{if .}} {{.}}{{end}}
+{{- end}}
+	{{.Name}} {{if .Optional}}*{{end}}{{.Type}}
+{{- end}}
+}
+{{end}}
+{{- if .HasColumns}}
+// {{.Row}} is a row returned by {{.Func}}.
+type {{.Row}} struct {
+{{- range .Columns}}
+{{- range .Comment}}
+	//{{if .}} {{.}}{{end}}
+{{- end}}
+	{{.Name}} {{.Type}}
+{{- end}}
+}
+{{end}}
+{{- range .Comment}}
+//{{if .}} {{.}}{{end}}
+{{- end}}
+func {{.Func}}(ctx context.Context, db DBTX{{if .HasParams}}, params {{.ParamStruct}}{{end}}) ({{if .HasColumns}}[]{{.Row}}{{else}}sql.Result{{end}}, error) {
+{{- range .Params}}{{if and .Optional .Used}}
+	var {{.Var}} interface{}{{if .Default}} = {{.Default}}{{end}}
+	if params.{{.Name}} != nil {
+		{{.Var}} = *params.{{.Name}}
+	}
+{{- end}}{{end}}
+{{- if .HasColumns}}
+	rows, err := db.QueryContext(ctx, {{.Const}}{{range .Args}}, {{.}}{{end}})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]{{.Row}}, 0)
+	for rows.Next() {
+		var i {{.Row}}
+		if err := rows.Scan({{range $n, $c := .Columns}}{{if $n}}, {{end}}&i.{{$c.Name}}{{end}}); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+{{- else}}
+	return db.ExecContext(ctx, {{.Const}}{{range .Args}}, {{.}}{{end}})
+{{- end}}
+}
+{{end}}`))
+
+// Go writes a Go source file with a function per query, a params struct
+// built from its @param annotations and a row struct built from its
+// @column annotations. The output is gofmt formatted and depends only on
+// the order of docs.
+func Go(w io.Writer, docs []compiler.QueryDoc, opts GoOptions) error {
+	if opts.Package == "" {
+		opts.Package = "queries"
+	}
+	if !token.IsIdentifier(opts.Package) {
+		return fmt.Errorf("%q isn't a Go package name", opts.Package)
+	}
+	data := struct {
+		Package string
+		Math    bool
+		Time    bool
+		Queries []goQuery
+	}{Package: opts.Package, Queries: make([]goQuery, 0, len(docs))}
+	// seen are the package level names generated, by the query they're
+	// generated for.
+	seen := map[string]string{"DBTX": "the DBTX interface"}
+	for _, doc := range docs {
+		q, err := goQueryFor(doc, opts.Placeholder)
+		if err != nil {
+			return fmt.Errorf("%s: %v", doc.Name, err)
+		}
+		names := []string{q.Func, q.Const}
+		if q.HasParams {
+			names = append(names, q.ParamStruct)
+		}
+		if q.HasColumns {
+			names = append(names, q.Row)
+		}
+		for _, name := range names {
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("%s and %s both generate %s", other, doc.Name, name)
+			}
+			seen[name] = doc.Name
+		}
+		for _, f := range append(q.Params, q.Columns...) {
+			data.Time = data.Time || f.Type == "time.Time"
+			data.Math = data.Math || strings.HasPrefix(f.Default, "math.")
+		}
+		data.Queries = append(data.Queries, q)
+	}
+	b := bytes.Buffer{}
+	err := goTemplate.Execute(&b, data)
+	if err != nil {
+		return err
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func goQueryFor(doc compiler.QueryDoc, style bind.Style) (goQuery, error) {
+	name := exported(doc.Name)
+	q := goQuery{
+		Func:        name,
+		Const:       camel(doc.Name) + "SQL",
+		Comment:     comment(fmt.Sprintf("%s runs the %q query.", name, firstNonEmpty(doc.Title, doc.Name)), doc.Description),
+		Row:         name + "Row",
+		ParamStruct: name + "Params",
+		HasParams:   len(doc.Params) > 0,
+		HasColumns:  len(doc.Output.Columns) > 0,
+		Args:        make([]string, 0),
+	}
+	sql, refs, err := bind.Rewrite(doc.SQL, style)
+	if err != nil {
+		return q, err
+	}
+	q.SQL = goString(sql)
+	params := make(map[string]int)
+	fields := make(map[string]string)
+	for i, p := range doc.Params {
+		f := goField{
+			Name:     exported(p.ProperName),
+			Var:      "arg" + exported(p.ProperName),
+			Type:     goType(p.Type),
+			Comment:  fieldComment(p.Blurb, p.Description),
+			Optional: !p.Required,
+		}
+		if other, ok := fields[f.Name]; ok {
+			return q, fmt.Errorf("params %s and %s both generate %s", other, p.ProperName, f.Name)
+		}
+		fields[f.Name] = p.ProperName
+		if p.Default != nil {
+			f.Default, err = goLiteral(p.Type, *p.Default)
+			if err != nil {
+				return q, fmt.Errorf("bad default for param %s: %v", p.ProperName, err)
+			}
+		}
+		params[p.ProperName] = i
+		q.Params = append(q.Params, f)
+	}
+	for _, ref := range refs {
+		i, ok := params[ref]
+		if !ok {
+			return q, fmt.Errorf("${%s} is not a documented @param", ref)
+		}
+		q.Params[i].Used = true
+		f := q.Params[i]
+		if f.Optional {
+			q.Args = append(q.Args, f.Var)
+		} else {
+			q.Args = append(q.Args, "params."+f.Name)
+		}
+	}
+	fields = make(map[string]string)
+	for _, c := range doc.Output.Columns {
+		f := goField{
+			Name:    exported(c.ProperName),
+			Type:    goType(c.Type),
+			Comment: fieldComment(c.Blurb, c.Description),
+		}
+		if other, ok := fields[f.Name]; ok {
+			return q, fmt.Errorf("columns %s and %s both generate %s", other, c.ProperName, f.Name)
+		}
+		fields[f.Name] = c.ProperName
+		q.Columns = append(q.Columns, f)
+	}
+	return q, nil
+}
+
+func goType(t compiler.Type) string {
+	switch t {
+	case compiler.Integer:
+		return "int64"
+	case compiler.Number:
+		return "float64"
+	case compiler.Boolean:
+		return "bool"
+	case compiler.Date, compiler.DateTime:
+		return "time.Time"
+	}
+	return "string"
+}
+
+// goLiteral renders a default as the value it converts to. Dates are
+// passed to the driver as strings.
+func goLiteral(t compiler.Type, value string) (string, error) {
+	v, err := t.Value(value)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case int64:
+		return "int64(" + strconv.FormatInt(v, 10) + ")", nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "math.NaN()", nil
+		case math.IsInf(v, 1):
+			return "math.Inf(1)", nil
+		case math.IsInf(v, -1):
+			return "math.Inf(-1)", nil
+		}
+		return "float64(" + strconv.FormatFloat(v, 'g', -1, 64) + ")", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return strconv.Quote(value), nil
+}
+
+// goString quotes s as a raw string where it can.
+func goString(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// comment joins the non empty parts into comment lines.
+func comment(parts ...string) []string {
+	lines := make([]string, 0)
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		for _, line := range strings.Split(part, "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+func fieldComment(blurb, description string) []string {
+	if blurb != "" && description != "" {
+		return comment(blurb + ": " + description)
+	}
+	return comment(blurb, description)
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var docs = []compiler.QueryDoc{
+	{
+		Name:  "active_users",
+		Title: "Active users",
+		SQL:   "SELECT id, name FROM users WHERE seen > ${since} AND team = ${team}",
+		Params: []compiler.Param{
+			{ProperName: "since", Type: compiler.Date, Required: true},
+			{ProperName: "team", Type: compiler.Integer},
+		},
+		Output: compiler.Table{Columns: []compiler.Column{
+			{ProperName: "id", Type: compiler.Integer},
+			{ProperName: "name", Type: compiler.String},
+		}},
+	},
+	{
+		Name: "purge",
+		SQL:  "DELETE FROM users",
+	},
+}
+
+func TestGo(t *testing.T) {
+	b := bytes.Buffer{}
+	err := Go(&b, docs, GoOptions{Package: "db"})
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	_, err = parser.ParseFile(token.NewFileSet(), "queries.go", b.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("Generated code doesn't parse: %v\n%s", err, b.String())
+	}
+	for _, want := range []string{
+		"package db",
+		"type ActiveUsersParams struct",
+		"Team  *int64",
+		"type ActiveUsersRow struct",
+		"rows.Scan(&i.ID, &i.Name)",
+		"db.QueryContext(ctx, activeUsersSQL, params.Since, argTeam)",
+		"func Purge(ctx context.Context, db DBTX) (sql.Result, error)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Generated code is missing %q\n%s", want, b.String())
+		}
+	}
+	again := bytes.Buffer{}
+	Go(&again, docs, GoOptions{Package: "db"})
+	if again.String() != b.String() {
+		t.Errorf("Generated code isn't deterministic")
+	}
+}
+
+func TestGoUndocumentedParam(t *testing.T) {
+	doc := compiler.QueryDoc{Name: "bad", SQL: "SELECT ${nope}"}
+	err := Go(&bytes.Buffer{}, []compiler.QueryDoc{doc}, GoOptions{})
+	if err == nil {
+		t.Errorf("Expected an error for an undocumented param")
+	}
+}
+
+// typeCheck fails t unless src compiles.
+func typeCheck(t *testing.T, src []byte) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "queries.go", src, 0)
+	if err != nil {
+		t.Fatalf("Generated code doesn't parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("queries", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("Generated code doesn't compile: %v\n%s", err, src)
+	}
+}
+
+func TestGoNames(t *testing.T) {
+	params := make([]compiler.Param, 0)
+	refs := make([]string, 0)
+	for _, name := range []string{"type", "db", "ctx", "err", "rows", "params", "items", "i", "make", "append", "nil"} {
+		params = append(params, compiler.Param{ProperName: name, Type: compiler.String})
+		refs = append(refs, "${"+name+"}")
+	}
+	doc := compiler.QueryDoc{
+		Name:   "clash",
+		SQL:    "SELECT " + strings.Join(refs, ", "),
+		Params: params,
+		Output: compiler.Table{Columns: []compiler.Column{{ProperName: "type", Type: compiler.String}}},
+	}
+	b := bytes.Buffer{}
+	if err := Go(&b, []compiler.QueryDoc{doc}, GoOptions{}); err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	typeCheck(t, b.Bytes())
+}
+
+func TestGoDefaults(t *testing.T) {
+	defaults := map[string]compiler.Type{"Inf": compiler.Number, "-inf": compiler.Number, "NaN": compiler.Number, "+5": compiler.Integer, "1e3": compiler.Number, "t": compiler.Boolean}
+	params := make([]compiler.Param, 0)
+	refs := make([]string, 0)
+	for value, typ := range defaults {
+		value := value
+		name := fmt.Sprintf("p%d", len(params))
+		params = append(params, compiler.Param{ProperName: name, Type: typ, Default: &value})
+		refs = append(refs, "${"+name+"}")
+	}
+	doc := compiler.QueryDoc{Name: "defaults", SQL: "SELECT " + strings.Join(refs, ", "), Params: params}
+	b := bytes.Buffer{}
+	if err := Go(&b, []compiler.QueryDoc{doc}, GoOptions{}); err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	typeCheck(t, b.Bytes())
+	for _, want := range []string{"math.Inf(1)", "math.Inf(-1)", "math.NaN()", "int64(5)", "float64(1000)", "= true"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Generated code is missing %q\n%s", want, b.String())
+		}
+	}
+	bad := "soon"
+	doc.Params = []compiler.Param{{ProperName: "n", Type: compiler.Integer, Default: &bad}}
+	doc.SQL = "SELECT ${n}"
+	if err := Go(&bytes.Buffer{}, []compiler.QueryDoc{doc}, GoOptions{}); err == nil {
+		t.Errorf("Expected an error for a default that isn't an integer")
+	}
+}
+
+func TestGoCollisions(t *testing.T) {
+	cases := map[string][]compiler.QueryDoc{
+		"x and x_row both generate XRow": {
+			{Name: "x", SQL: "SELECT 1", Output: compiler.Table{Columns: []compiler.Column{{ProperName: "one", Type: compiler.Integer}}}},
+			{Name: "x_row", SQL: "SELECT 1"},
+		},
+		"a and A both generate A": {
+			{Name: "a", SQL: "SELECT 1"},
+			{Name: "A", SQL: "SELECT 1"},
+		},
+		"q: params user_id and userID both generate UserID": {{
+			Name:   "q",
+			SQL:    "SELECT 1",
+			Params: []compiler.Param{{ProperName: "user_id", Type: compiler.String}, {ProperName: "userID", Type: compiler.String}},
+		}},
+		"q: columns a_b and aB both generate AB": {{
+			Name:   "q",
+			SQL:    "SELECT 1",
+			Output: compiler.Table{Columns: []compiler.Column{{ProperName: "a_b"}, {ProperName: "aB"}}},
+		}},
+	}
+	for want, docs := range cases {
+		err := Go(&bytes.Buffer{}, docs, GoOptions{})
+		if err == nil || err.Error() != want {
+			t.Errorf("Wrong error. Got %v want %v", err, want)
+		}
+	}
+	if err := Go(&bytes.Buffer{}, nil, GoOptions{Package: "type"}); err == nil {
+		t.Errorf("Expected an error for a keyword package name")
+	}
+}
@@ -0,0 +1,56 @@
+package codegen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Initialisms that Go style keeps upper case.
+var initialisms = map[string]bool{
+	"api": true, "db": true, "html": true, "http": true, "id": true,
+	"ip": true, "json": true, "sql": true, "uri": true, "url": true,
+	"uuid": true, "xml": true,
+}
+
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// exported converts a snake_case or spaced name to an exported Go
+// identifier, e.g. "user_id" becomes "UserID".
+func exported(name string) string {
+	b := strings.Builder{}
+	for _, w := range words(name) {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
+}
+
+// camel converts a name to a lower camel case identifier, e.g. "user_id"
+// becomes "userID".
+func camel(name string) string {
+	ws := words(name)
+	if len(ws) == 0 {
+		return "x"
+	}
+	first := strings.ToLower(ws[0])
+	if unicode.IsDigit([]rune(first)[0]) {
+		first = "x" + first
+	}
+	rest := ""
+	if len(ws) > 1 {
+		rest = exported(strings.Join(ws[1:], "_"))
+	}
+	return first + rest
+}
@@ -0,0 +1,154 @@
+package codegen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TypeScript writes a .d.ts file with an interface for each query's params
+// and each output table's rows, documented with JSDoc taken from the blurbs
+// and descriptions.
+func TypeScript(w io.Writer, docs []compiler.QueryDoc) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprint(b, "// Code generated by DocStringParser. DO NOT EDIT.\n")
+	seen := make(map[string]string)
+	for _, doc := range docs {
+		name := exported(doc.Name)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("queries %s and %s both generate %s", other, doc.Name, name)
+		}
+		seen[name] = doc.Name
+		if len(doc.Params) > 0 {
+			fmt.Fprint(b, "\n")
+			jsDoc(b, "", comment(fmt.Sprintf("Parameters of the %q query.", firstNonEmpty(doc.Title, doc.Name)), doc.Description), nil)
+			fmt.Fprintf(b, "export interface %sParams {\n", name)
+			for _, p := range doc.Params {
+				tags := make([]string, 0)
+				if p.Default != nil {
+					def, err := tsLiteral(p.Type, *p.Default)
+					if err != nil {
+						return fmt.Errorf("%s: bad default for param %s: %v", doc.Name, p.ProperName, err)
+					}
+					tags = append(tags, "@default "+def)
+				}
+				jsDoc(b, "  ", comment(p.Blurb, p.Description), tags)
+				optional := ""
+				if !p.Required {
+					optional = "?"
+				}
+				typ, err := tsParamType(p)
+				if err != nil {
+					return fmt.Errorf("%s: bad enum value for param %s: %v", doc.Name, p.ProperName, err)
+				}
+				fmt.Fprintf(b, "  %s%s: %s;\n", tsProperty(p.ProperName), optional, typ)
+			}
+			fmt.Fprint(b, "}\n")
+		}
+		if len(doc.Output.Columns) > 0 {
+			fmt.Fprint(b, "\n")
+			jsDoc(b, "", comment(fmt.Sprintf("A row of %s.", firstNonEmpty(doc.Output.Title, doc.Title, doc.Name)), doc.Output.Description), nil)
+			fmt.Fprintf(b, "export interface %sRow {\n", name)
+			for _, c := range doc.Output.Columns {
+				jsDoc(b, "  ", comment(c.Blurb, c.Description), nil)
+				fmt.Fprintf(b, "  %s: %s;\n", tsProperty(c.ProperName), tsType(c.Type))
+			}
+			fmt.Fprint(b, "}\n")
+		}
+	}
+	return b.Flush()
+}
+
+func jsDoc(w io.Writer, indent string, lines []string, tags []string) {
+	if len(tags) > 0 {
+		lines = append(lines, tags...)
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s/**\n", indent)
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, "*/", "*\\/")
+		if line == "" {
+			fmt.Fprintf(w, "%s *\n", indent)
+		} else {
+			fmt.Fprintf(w, "%s * %s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(w, "%s */\n", indent)
+}
+
+func tsProperty(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// tsParamType is a union of a param's enum values, or its plain type if
+// it has none or one isn't a literal type, as NaN and Infinity aren't.
+func tsParamType(p compiler.Param) (string, error) {
+	if len(p.Enum) == 0 {
+		return tsType(p.Type), nil
+	}
+	values := make([]string, 0, len(p.Enum))
+	plain := false
+	for _, e := range p.Enum {
+		value, err := tsLiteral(p.Type, e)
+		if err != nil {
+			return "", err
+		}
+		plain = plain || value == "NaN" || strings.HasSuffix(value, "Infinity")
+		values = append(values, value)
+	}
+	if plain {
+		return tsType(p.Type), nil
+	}
+	return strings.Join(values, " | "), nil
+}
+
+// tsType maps a type to its JSON representation. Dates travel as ISO 8601
+// strings.
+func tsType(t compiler.Type) string {
+	switch t {
+	case compiler.Integer, compiler.Number:
+		return "number"
+	case compiler.Boolean:
+		return "boolean"
+	}
+	return "string"
+}
+
+// tsLiteral writes value as the JavaScript literal of what it converts
+// to, e.g. "+5" as 5 and "t" as true.
+func tsLiteral(t compiler.Type, value string) (string, error) {
+	v, err := t.Value(value)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN", nil
+		case math.IsInf(v, 1):
+			return "Infinity", nil
+		case math.IsInf(v, -1):
+			return "-Infinity", nil
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return strconv.Quote(value), nil
+}
@@ -0,0 +1,77 @@
+package codegen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+func TestTypeScript(t *testing.T) {
+	limit := "10"
+	doc := compiler.QueryDoc{
+		Name:  "orders",
+		Title: "Orders",
+		Params: []compiler.Param{
+			{ProperName: "status", Type: compiler.String, Blurb: "Status", Description: "Order status", Required: true, Enum: []string{"open", "closed"}},
+			{ProperName: "limit", Type: compiler.Integer, Default: &limit},
+		},
+		Output: compiler.Table{Columns: []compiler.Column{
+			{ProperName: "id", Type: compiler.Integer, Description: "ends a comment */"},
+			{ProperName: "created-at", Type: compiler.DateTime},
+		}},
+	}
+	b := bytes.Buffer{}
+	err := TypeScript(&b, []compiler.QueryDoc{doc})
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	for _, want := range []string{
+		"export interface OrdersParams {",
+		"   * Status\n   *\n   * Order status\n",
+		`  status: "open" | "closed";`,
+		"   * @default 10\n",
+		"  limit?: number;",
+		"export interface OrdersRow {",
+		"   * ends a comment *\\/\n",
+		`  "created-at": string;`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Generated definitions are missing %q\n%s", want, b.String())
+		}
+	}
+}
+
+func TestTypeScriptLiterals(t *testing.T) {
+	plus, inf, yes := "+5", "Inf", "t"
+	doc := compiler.QueryDoc{
+		Name: "q",
+		Params: []compiler.Param{
+			{ProperName: "flag", Type: compiler.Boolean, Enum: []string{"1", "f"}, Default: &yes},
+			{ProperName: "n", Type: compiler.Integer, Enum: []string{"+5", "-2"}, Default: &plus},
+			{ProperName: "x", Type: compiler.Number, Enum: []string{"1.50", "NaN"}, Default: &inf},
+		},
+	}
+	b := bytes.Buffer{}
+	err := TypeScript(&b, []compiler.QueryDoc{doc})
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	for _, want := range []string{
+		"   * @default true\n",
+		"  flag?: true | false;",
+		"   * @default 5\n",
+		"  n?: 5 | -2;",
+		"   * @default Infinity\n",
+		"  x?: number;",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Generated definitions are missing %q\n%s", want, b.String())
+		}
+	}
+	doc.Params = []compiler.Param{{ProperName: "n", Type: compiler.Integer, Enum: []string{"five"}}}
+	if err := TypeScript(&bytes.Buffer{}, []compiler.QueryDoc{doc}); err == nil {
+		t.Errorf("Expected an error for an enum value that isn't an integer")
+	}
+}
@@ -1,16 +1,51 @@
 package compiler
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
 
+	"github.com/christopher-henderson/DocStringParser/markdown"
 	"github.com/christopher-henderson/DocStringParser/tokenizer"
 )
 
 type QueryDoc struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Params      []Param `json:"params"`
-	Output      Table   `json:"output"`
+	Name string `json:"name"`
+	// Named is whether the doc gave its @name, rather than the name being
+	// derived from its title.
+	Named           bool               `json:"-"`
+	Source          string             `json:"source,omitempty"`
+	Title           string             `json:"title"`
+	Description     string             `json:"description"`
+	DescriptionHTML string             `json:"descriptionHtml"`
+	Markup          *markdown.Document `json:"-"`
+	Params          []Param            `json:"params"`
+	Output          Table              `json:"output"`
+	SQL             string             `json:"sql"`
+	See             []string           `json:"see,omitempty"`
+	Examples        []Example          `json:"examples,omitempty"`
+	Owner           string             `json:"owner,omitempty"`
+	Team            string             `json:"team,omitempty"`
+	Tags            []string           `json:"tags,omitempty"`
+	Since           string             `json:"since,omitempty"`
+	Deprecated      *Deprecation       `json:"deprecated,omitempty"`
+	Policy          Policy             `json:"policy"`
+	// Requires lists the roles allowed to see and run the query; any one
+	// of them will do.
+	Requires []string `json:"requires,omitempty"`
+	// Define names a doc of shared definitions rather than a query, and
+	// Includes the definitions a doc takes its params and columns from.
+	// Both are left for Resolve.
+	Define   string   `json:"define,omitempty"`
+	Includes []string `json:"includes,omitempty"`
+	// Incomplete docs failed to compile in part and are only as much as
+	// could be made out, with Diagnostics saying why.
+	Incomplete  bool        `json:"incomplete,omitempty"`
+	Diagnostics Diagnostics `json:"diagnostics,omitempty"`
+	// Pos is where the doc opens and End where its statement ends.
+	Pos tokenizer.Position `json:"-"`
+	End tokenizer.Position `json:"-"`
 }
 
 func NewQueryDocs() (q QueryDoc) {
@@ -19,9 +54,11 @@ func NewQueryDocs() (q QueryDoc) {
 }
 
 type Table struct {
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Columns     []Column `json:"columns"`
+	Title           string             `json:"title"`
+	Description     string             `json:"description"`
+	DescriptionHTML string             `json:"descriptionHtml"`
+	Markup          *markdown.Document `json:"-"`
+	Columns         []Column           `json:"columns"`
 }
 
 func NewTable() Table {
@@ -29,35 +66,157 @@ func NewTable() Table {
 }
 
 type Param struct {
-	ProperName  string `json:"properName"`
-	Blurb       string `json:"blurb"`
-	Description string `json:"description"`
+	ProperName      string             `json:"properName"`
+	Type            Type               `json:"type"`
+	Blurb           string             `json:"blurb"`
+	Description     string             `json:"description"`
+	DescriptionHTML string             `json:"descriptionHtml"`
+	Markup          *markdown.Document `json:"-"`
+	Required        bool               `json:"required"`
+	Default         *string            `json:"default,omitempty"`
+	Enum            []string           `json:"enum,omitempty"`
+	// Use names the shared definition the param is, from @param use:name
+	// or use:definition.name.
+	Use string `json:"use,omitempty"`
 }
 
 type Column struct {
-	ProperName  string `json:"properName"`
-	Blurb       string `json:"blurb"`
-	Description string `json:"description"`
+	ProperName      string             `json:"properName"`
+	Type            Type               `json:"type"`
+	Blurb           string             `json:"blurb"`
+	Description     string             `json:"description"`
+	DescriptionHTML string             `json:"descriptionHtml"`
+	Markup          *markdown.Document `json:"-"`
+	// Sensitive columns are redacted for callers without access to them.
+	Sensitive bool `json:"sensitive,omitempty"`
+	// Use names the shared definition the column is, from @column use:name
+	// or use:definition.name.
+	Use string `json:"use,omitempty"`
+}
+
+// Slug derives a query name from its title, e.g. "Active Users" becomes
+// "active_users".
+func Slug(title string) string {
+	b := strings.Builder{}
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteRune('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// Describe parses raw description text as markdown, returning both the
+// tree and its HTML rendering.
+func Describe(raw string) (*markdown.Document, string) {
+	doc := markdown.Parse(raw)
+	return doc, doc.HTML()
+}
+
+// Rehydrate restores what a doc's JSON leaves out: the parsed
+// descriptions.
+func (q *QueryDoc) Rehydrate() {
+	WalkDoc(rehydrator{}, q)
+}
+
+type rehydrator struct {
+	NopVisitor
+}
+
+func parse(raw string) *markdown.Document {
+	if raw == "" {
+		return nil
+	}
+	return markdown.Parse(raw)
+}
+
+func (rehydrator) EnterDoc(q *QueryDoc) Action {
+	q.Markup = parse(q.Description)
+	return Continue
+}
+
+func (rehydrator) EnterParam(p *Param) Action {
+	p.Markup = parse(p.Description)
+	return Continue
+}
+
+func (rehydrator) EnterTable(t *Table) Action {
+	t.Markup = parse(t.Description)
+	return Continue
+}
+
+func (rehydrator) EnterColumn(c *Column) Action {
+	c.Markup = parse(c.Description)
+	return Continue
+}
+
+// Deprecation says why a query shouldn't be used and what to use instead.
+type Deprecation struct {
+	Reason     string `json:"reason"`
+	ReplacedBy string `json:"replacedBy,omitempty"`
+}
+
+// Example is a set of param values and the rows the query should return
+// for them, as CSV with a header, either inline or in a file relative to
+// the doc's source.
+type Example struct {
+	Name       string             `json:"name"`
+	Values     map[string]string  `json:"values"`
+	Expect     string             `json:"expect,omitempty"`
+	ExpectFile string             `json:"expectFile,omitempty"`
+	Pos        tokenizer.Position `json:"-"`
 }
 
 type Compiler struct {
-	Tokens  []tokenizer.Tokener
-	docList []QueryDoc
-	state   int
+	Tokens      []tokenizer.Tokener
+	ctx         context.Context
+	docList     []QueryDoc
+	diagnostics Diagnostics
+	state       int
 }
 
 func NewCompiler(tokens []tokenizer.Tokener) Compiler {
 	return Compiler{Tokens: tokens, docList: make([]QueryDoc, 0), state: 0}
 }
 
+// Compile compiles every doc in tokens. Docs that fail to compile are
+// returned as far as they go, marked Incomplete, and reported by returning
+// Diagnostics alongside them.
 func Compile(tokens []tokenizer.Tokener) ([]QueryDoc, error) {
-	c := Compiler{Tokens: tokens}
+	return CompileContext(context.Background(), tokens)
+}
+
+// CompileContext is Compile, giving up between docs once ctx is done.
+func CompileContext(ctx context.Context, tokens []tokenizer.Tokener) ([]QueryDoc, error) {
+	c := Compiler{Tokens: tokens, ctx: ctx}
 	return c.compile()
 }
 
+func (c *Compiler) Diagnostics() Diagnostics {
+	return c.diagnostics
+}
+
+func (c *Compiler) errorf(t tokenizer.Tokener, format string, args ...interface{}) error {
+	return Diagnostic{Pos: t.Pos(), Severity: SeverityError, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *Compiler) expected(t tokenizer.Tokener, what string) error {
+	if _, ok := t.(tokenizer.ErrorToken); ok {
+		return c.errorf(t, "%v", t.Original())
+	}
+	return c.errorf(t, "expected %v, got %q", what, t.Original())
+}
+
 func (c *Compiler) next() (tokenizer.Tokener, error) {
 	if c.state >= len(c.Tokens) {
-		return nil, errors.New("no")
+		var pos tokenizer.Position
+		if len(c.Tokens) > 0 {
+			pos = c.Tokens[len(c.Tokens)-1].Pos()
+		}
+		return nil, Diagnostic{Pos: pos, Severity: SeverityError, Message: "unexpected end of input"}
 	}
 	defer func() {
 		c.state += 1
@@ -66,161 +225,502 @@ func (c *Compiler) next() (tokenizer.Tokener, error) {
 }
 
 func (c *Compiler) compile() ([]QueryDoc, error) {
+	c.docList = make([]QueryDoc, 0)
 	for doc, err := c.next(); err == nil; doc, err = c.next() {
 		switch doc.(type) {
 		case tokenizer.OpenDoc:
-			qdoc, err := c.compileDoc()
-			if err != nil {
-				return nil, err
+			if c.ctx != nil && c.ctx.Err() != nil {
+				return c.docList, c.ctx.Err()
 			}
+			qdoc := c.compileDoc()
+			if qdoc.Name == "" {
+				qdoc.Name = Slug(qdoc.Title)
+			}
+			qdoc.Pos = doc.Pos()
 			c.docList = append(c.docList, qdoc)
+		case tokenizer.ErrorToken:
+			c.report(c.errorf(doc, "%v", doc.Original()))
 		default:
-			return nil, errors.New("Unexpected type at top leve.")
+			c.report(c.errorf(doc, "unexpected %q outside of a doc", doc.Original()))
 		}
 	}
+	if len(c.diagnostics) > 0 {
+		return c.docList, c.diagnostics
+	}
 	return c.docList, nil
 }
 
-func (c *Compiler) compileDoc() (QueryDoc, error) {
+func (c *Compiler) report(err error) {
+	c.diagnostics = append(c.diagnostics, AsDiagnostics(err)...)
+}
+
+// resync reports err and backs up to the token it choked on, if that
+// token starts something else, so compiling carries on from there. from is
+// the position just past the annotation being compiled, which is never
+// backed up past.
+func (c *Compiler) resync(from int, err error) {
+	c.report(err)
+	if c.state <= from || c.state > len(c.Tokens) {
+		return
+	}
+	switch c.Tokens[c.state-1].(type) {
+	case tokenizer.Text, tokenizer.BareWord, tokenizer.ErrorToken:
+	default:
+		c.state -= 1
+	}
+}
+
+// compileDoc compiles as much of a doc as it can, carrying on past
+// anything malformed to the next annotation. Docs with errors are marked
+// Incomplete.
+func (c *Compiler) compileDoc() QueryDoc {
 	q := NewQueryDocs()
+	before := len(c.diagnostics)
 	for t, err := c.next(); err == nil; t, err = c.next() {
-		switch t.(type) {
-		case tokenizer.Title:
-			title, err := c.next()
-			if err != nil {
-				return q, err
+		if end, ok := t.(tokenizer.CloseDoc); ok {
+			q.SQL = end.Statement
+			q.End = t.Pos()
+			if q.Policy.ReadOnly && !IsReadOnly(q.SQL) {
+				c.report(c.errorf(t, "query is @readonly but its statement can write"))
 			}
-			switch title.(type) {
-			case tokenizer.Text:
-				q.Title = title.Original()
-			default:
-				return q, errors.New("Bad parse tree")
+			if err := c.checkExamples(q); err != nil {
+				c.report(err)
 			}
-		case tokenizer.Desc:
-			desc, err := c.next()
-			if err != nil {
-				return q, err
+			return c.finish(q, before)
+		}
+		from := c.state
+		if err := c.compileAnnotation(t, &q); err != nil {
+			c.resync(from, err)
+		}
+	}
+	c.report(c.errorf(c.Tokens[len(c.Tokens)-1], "unexpected end of input"))
+	return c.finish(q, before)
+}
+
+// finish marks q Incomplete if anything was reported since before.
+func (c *Compiler) finish(q QueryDoc, before int) QueryDoc {
+	if len(c.diagnostics) > before {
+		q.Incomplete = true
+		q.Diagnostics = append(Diagnostics(nil), c.diagnostics[before:]...)
+	}
+	return q
+}
+
+func (c *Compiler) compileAnnotation(t tokenizer.Tokener, q *QueryDoc) error {
+	switch t.(type) {
+	case tokenizer.Title:
+		title, err := c.next()
+		if err != nil {
+			return err
+		}
+		switch title.(type) {
+		case tokenizer.Text:
+			q.Title = title.Original()
+		default:
+			return c.expected(title, "text after @title")
+		}
+	case tokenizer.Name:
+		name, err := c.next()
+		if err != nil {
+			return err
+		}
+		switch name.(type) {
+		case tokenizer.BareWord:
+			q.Name, q.Named = name.Original(), true
+		default:
+			return c.expected(name, "a name after @name")
+		}
+	case tokenizer.See:
+		name, err := c.next()
+		if err != nil {
+			return err
+		}
+		switch name.(type) {
+		case tokenizer.BareWord:
+			q.See = append(q.See, name.Original())
+		default:
+			return c.expected(name, "a query name after @see")
+		}
+	case tokenizer.Owner, tokenizer.Team, tokenizer.Since:
+		value, err := c.next()
+		if err != nil {
+			return err
+		}
+		if _, ok := value.(tokenizer.Text); !ok {
+			return c.expected(value, "text after @"+t.Original())
+		}
+		switch t.(type) {
+		case tokenizer.Owner:
+			q.Owner = value.Original()
+		case tokenizer.Team:
+			q.Team = value.Original()
+		default:
+			q.Since = value.Original()
+		}
+	case tokenizer.Timeout, tokenizer.Cache, tokenizer.MaxRows:
+		return c.compilePolicy(t, &q.Policy)
+	case tokenizer.ReadOnly:
+		q.Policy.ReadOnly = true
+	case tokenizer.Requires:
+		for role, err := c.next(); err == nil; role, err = c.next() {
+			if _, ok := role.(tokenizer.BareWord); !ok {
+				c.state -= 1
+				break
 			}
-			switch desc.(type) {
-			case tokenizer.Text:
-				q.Description = desc.Original()
-			default:
-				return q, errors.New("Bad parse tree")
+			q.Requires = append(q.Requires, role.Original())
+		}
+		if len(q.Requires) == 0 {
+			return c.errorf(t, "@requires needs at least one role")
+		}
+	case tokenizer.Define:
+		name, err := c.next()
+		if err != nil {
+			return err
+		}
+		if _, ok := name.(tokenizer.BareWord); !ok {
+			return c.expected(name, "a name after @define")
+		}
+		q.Define = name.Original()
+	case tokenizer.Include:
+		n := len(q.Includes)
+		for name, err := c.next(); err == nil; name, err = c.next() {
+			if _, ok := name.(tokenizer.BareWord); !ok {
+				c.state -= 1
+				break
 			}
-		case tokenizer.Param:
-			p, err := c.compileParam()
-			if err != nil {
-				return q, err
+			q.Includes = append(q.Includes, name.Original())
+		}
+		if len(q.Includes) == n {
+			return c.errorf(t, "@include needs at least one definition")
+		}
+	case tokenizer.Tags:
+		for tag, err := c.next(); err == nil; tag, err = c.next() {
+			if _, ok := tag.(tokenizer.Text); !ok {
+				c.state -= 1
+				break
 			}
+			q.Tags = append(q.Tags, tag.Original())
+		}
+	case tokenizer.Deprecated:
+		reason, err := c.next()
+		if err != nil {
+			return err
+		}
+		if _, ok := reason.(tokenizer.Text); !ok {
+			return c.expected(reason, "a reason after @deprecated")
+		}
+		q.Deprecated = &Deprecation{Reason: reason.Original()}
+		replacement, err := c.next()
+		if err != nil {
+			return err
+		}
+		if _, ok := replacement.(tokenizer.BareWord); ok {
+			q.Deprecated.ReplacedBy = replacement.Original()
+		} else {
+			c.state -= 1
+		}
+	case tokenizer.Desc:
+		desc, err := c.next()
+		if err != nil {
+			return err
+		}
+		switch desc.(type) {
+		case tokenizer.Text:
+			q.Description = desc.Original()
+			q.Markup, q.DescriptionHTML = Describe(q.Description)
+		default:
+			return c.expected(desc, "text after @description")
+		}
+	case tokenizer.Param:
+		p, err := c.compileParam()
+		if p.ProperName != "" {
 			q.Params = append(q.Params, p)
-		case tokenizer.Table:
-			t, err := c.compileTable()
-			if err != nil {
-				return q, err
-			}
-			q.Output = t
-		case tokenizer.CloseDoc:
-			return q, nil
 		}
+		if err != nil {
+			return err
+		}
+	case tokenizer.Required, tokenizer.Default, tokenizer.Enum:
+		if len(q.Params) == 0 {
+			return c.errorf(t, "@%v must follow a @param", t.Original())
+		}
+		err := c.compileParamModifier(t, &q.Params[len(q.Params)-1])
+		if err != nil {
+			return err
+		}
+	case tokenizer.Table:
+		q.Output = c.compileTable()
+	case tokenizer.Example:
+		ex, err := c.compileExample(t)
+		if err != nil {
+			return err
+		}
+		q.Examples = append(q.Examples, ex)
+	case tokenizer.ErrorToken:
+		return c.errorf(t, "%v", t.Original())
 	}
-	return q, nil
+	return nil
 }
 
 func (c *Compiler) compileParam() (p Param, err error) {
-	name, err := c.next()
+	p.ProperName, p.Type, p.Blurb, p.Description, err = c.compileField("param")
+	if use, ok := strings.CutPrefix(p.ProperName, "use:"); ok {
+		p.ProperName, p.Use = use, use
+		return
+	}
+	p.Markup, p.DescriptionHTML = Describe(p.Description)
+	return
+}
+
+// compileField compiles the name, optional type, blurb and description
+// shared by params and columns, or just the name of a use:name reference
+// to a shared definition.
+func (c *Compiler) compileField(kind string) (name string, typ Type, blurb, description string, err error) {
+	t, err := c.next()
 	if err != nil {
 		return
 	}
-	switch name.(type) {
+	switch t.(type) {
 	case tokenizer.BareWord:
-		p.ProperName = name.Original()
+		name = t.Original()
+	default:
+		err = c.expected(t, "a "+kind+" name")
+		return
+	}
+	if use, ok := strings.CutPrefix(name, "use:"); ok {
+		if use == "" {
+			err = c.errorf(t, "expected a definition name after use:")
+		}
+		typ = String
+		return
 	}
-	blurb, err := c.next()
+	typ, err = c.compileType()
 	if err != nil {
 		return
 	}
-	switch blurb.(type) {
+	t, err = c.next()
+	if err != nil {
+		return
+	}
+	switch t.(type) {
 	case tokenizer.Text:
-		p.Blurb = blurb.Original()
+		blurb = t.Original()
+	default:
+		err = c.expected(t, "a blurb for "+kind+" "+name)
+		return
 	}
-	description, err := c.next()
+	t, err = c.next()
 	if err != nil {
 		return
 	}
-	switch description.(type) {
+	switch t.(type) {
 	case tokenizer.Text:
-		p.Description = description.Original()
+		description = t.Original()
+	default:
+		err = c.expected(t, "a description for "+kind+" "+name)
 	}
-
 	return
 }
 
-func (c *Compiler) compileTable() (Table, error) {
+// compileType compiles the optional type following a param or column name,
+// defaulting to String.
+func (c *Compiler) compileType() (Type, error) {
+	typ, err := c.next()
+	if err != nil {
+		return "", err
+	}
+	switch typ.(type) {
+	case tokenizer.BareWord:
+		parsed, err := ParseType(typ.Original())
+		if err != nil {
+			return "", c.errorf(typ, "%v", err)
+		}
+		return parsed, nil
+	}
+	c.state -= 1
+	return String, nil
+}
+
+func (c *Compiler) compileParamModifier(t tokenizer.Tokener, p *Param) error {
+	switch t.(type) {
+	case tokenizer.Required:
+		p.Required = true
+	case tokenizer.Default:
+		value, err := c.next()
+		if err != nil {
+			return err
+		}
+		switch value.(type) {
+		case tokenizer.Text:
+		default:
+			return c.expected(value, "text after @default")
+		}
+		if _, err := p.Type.Value(value.Original()); err != nil {
+			return c.errorf(value, "bad default for param %v: %v", p.ProperName, err)
+		}
+		d := value.Original()
+		p.Default = &d
+	case tokenizer.Enum:
+		p.Enum = make([]string, 0)
+		for value, err := c.next(); err == nil; value, err = c.next() {
+			if _, ok := value.(tokenizer.Text); !ok {
+				c.state -= 1
+				break
+			}
+			if _, err := p.Type.Value(value.Original()); err != nil {
+				return c.errorf(value, "bad enum value for param %v: %v", p.ProperName, err)
+			}
+			p.Enum = append(p.Enum, value.Original())
+		}
+	}
+	return nil
+}
+
+// compileTable compiles a @table, carrying on past anything malformed in
+// it like compileDoc.
+func (c *Compiler) compileTable() Table {
 	table := NewTable()
-	var err error
 	for t, err := c.next(); err == nil; t, err = c.next() {
+		from := c.state
 		switch t.(type) {
 		case tokenizer.Title:
 			title, err := c.next()
 			if err != nil {
-				return table, err
+				return table
 			}
 			switch title.(type) {
 			case tokenizer.Text:
 				table.Title = title.Original()
 			default:
-				return table, errors.New("Bad parse tree")
+				c.resync(from, c.expected(title, "text after @title"))
 			}
 		case tokenizer.Desc:
 			desc, err := c.next()
 			if err != nil {
-				return table, err
+				return table
 			}
 			switch desc.(type) {
 			case tokenizer.Text:
 				table.Description = desc.Original()
+				table.Markup, table.DescriptionHTML = Describe(table.Description)
 			default:
-				return table, errors.New("Bad parse tree")
+				c.resync(from, c.expected(desc, "text after @description"))
 			}
 		case tokenizer.Column:
-			c, err := c.compileColumn()
+			col, err := c.compileColumn()
+			if col.ProperName != "" {
+				table.Columns = append(table.Columns, col)
+			}
 			if err != nil {
-				return table, nil
+				c.resync(from, err)
+			}
+		case tokenizer.Sensitive:
+			if len(table.Columns) == 0 {
+				c.resync(from, c.errorf(t, "@sensitive must follow a @column"))
+				continue
 			}
-			table.Columns = append(table.Columns, c)
+			table.Columns[len(table.Columns)-1].Sensitive = true
+		case tokenizer.ErrorToken:
+			c.resync(from, c.errorf(t, "%v", t.Original()))
 		default:
 			c.state -= 1
-			return table, nil
+			return table
 		}
 	}
-	return table, err
+	return table
 }
 
-func (c *Compiler) compileColumn() (col Column, err error) {
+func (c *Compiler) compileExample(start tokenizer.Tokener) (Example, error) {
+	ex := Example{Values: make(map[string]string), Pos: start.Pos()}
 	name, err := c.next()
 	if err != nil {
-		return
+		return ex, err
 	}
 	switch name.(type) {
-	case tokenizer.BareWord:
-		col.ProperName = name.Original()
-	}
-	blurb, err := c.next()
-	if err != nil {
-		return
-	}
-	switch blurb.(type) {
 	case tokenizer.Text:
-		col.Blurb = blurb.Original()
+		ex.Name = name.Original()
+	default:
+		c.state -= 1
 	}
-	description, err := c.next()
-	if err != nil {
-		return
+	for t, err := c.next(); err == nil; t, err = c.next() {
+		switch t.(type) {
+		case tokenizer.Value:
+			param, err := c.next()
+			if err != nil {
+				return ex, err
+			}
+			if _, ok := param.(tokenizer.BareWord); !ok {
+				return ex, c.expected(param, "a param name after @value")
+			}
+			value, err := c.next()
+			if err != nil {
+				return ex, err
+			}
+			if _, ok := value.(tokenizer.Text); !ok {
+				return ex, c.expected(value, "text after @value "+param.Original())
+			}
+			ex.Values[param.Original()] = value.Original()
+		case tokenizer.Expect:
+			value, err := c.next()
+			if err != nil {
+				return ex, err
+			}
+			file := false
+			if _, ok := value.(tokenizer.BareWord); ok {
+				if value.Original() != "file" {
+					return ex, c.expected(value, `"file" or text after @expect`)
+				}
+				file = true
+				value, err = c.next()
+				if err != nil {
+					return ex, err
+				}
+			}
+			if _, ok := value.(tokenizer.Text); !ok {
+				return ex, c.expected(value, "text after @expect")
+			}
+			if file {
+				ex.ExpectFile = value.Original()
+			} else {
+				ex.Expect = value.Original()
+			}
+		default:
+			c.state -= 1
+			return ex, nil
+		}
 	}
-	switch description.(type) {
-	case tokenizer.Text:
-		col.Description = blurb.Original()
+	return ex, nil
+}
+
+// checkExamples checks example values against the params, which may be
+// declared after the examples.
+func (c *Compiler) checkExamples(q QueryDoc) error {
+	params := make(map[string]Param)
+	for _, p := range q.Params {
+		params[p.ProperName] = p
+	}
+	for _, ex := range q.Examples {
+		for name, value := range ex.Values {
+			p, ok := params[name]
+			if !ok {
+				return Diagnostic{Pos: ex.Pos, Severity: SeverityError, Message: fmt.Sprintf("example %q sets unknown param %v", ex.Name, name)}
+			}
+			if _, err := p.Type.Value(value); err != nil {
+				return Diagnostic{Pos: ex.Pos, Severity: SeverityError, Message: fmt.Sprintf("example %q has a bad value for param %v: %v", ex.Name, name, err)}
+			}
+		}
+		if ex.Expect != "" && ex.ExpectFile != "" {
+			return Diagnostic{Pos: ex.Pos, Severity: SeverityError, Message: fmt.Sprintf("example %q expects both inline rows and a file", ex.Name)}
+		}
 	}
+	return nil
+}
 
+func (c *Compiler) compileColumn() (col Column, err error) {
+	col.ProperName, col.Type, col.Blurb, col.Description, err = c.compileField("column")
+	if use, ok := strings.CutPrefix(col.ProperName, "use:"); ok {
+		col.ProperName, col.Use = use, use
+		return
+	}
+	col.Markup, col.DescriptionHTML = Describe(col.Description)
 	return
 }
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+const partialSource = `/**
+@title "Half written"
+@param id int "ID"
+@param when date "When" "When it happened"
+@table {
+	@title
+	@column name "Name" "The name"
+	@column
+}
+@timeout soon
+*/
+SELECT name FROM things WHERE id = ${id};
+/**
+@title "Fine"
+*/
+SELECT 1;`
+
+func TestPartialDocs(t *testing.T) {
+	tok := tokenizer.NewTokenizer(strings.NewReader(partialSource))
+	tok.Tokenize()
+	docs, err := Compile(tok.Tokens())
+	if err == nil {
+		t.Fatal("Expected diagnostics for the half written doc.")
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Wrong number of docs. Got %v want 2", len(docs))
+	}
+	q := docs[0]
+	if !q.Incomplete || q.Title != "Half written" || q.SQL == "" {
+		t.Errorf("Wrong partial doc. Got %+v", q)
+	}
+	if len(q.Params) != 2 || q.Params[1].Description != "When it happened" {
+		t.Errorf("Wrong partial params. Got %+v", q.Params)
+	}
+	if len(q.Output.Columns) != 1 || q.Output.Columns[0].ProperName != "name" {
+		t.Errorf("Wrong partial columns. Got %+v", q.Output.Columns)
+	}
+	lines := make([]int, 0)
+	for _, d := range q.Diagnostics {
+		lines = append(lines, d.Pos.Line)
+	}
+	want := []int{4, 7, 9, 10}
+	if len(lines) != len(want) {
+		t.Fatalf("Wrong diagnostics. Got %v want lines %v", q.Diagnostics, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Wrong diagnostic line. Got %v want %v", lines[i], want[i])
+		}
+	}
+	if docs[1].Incomplete || docs[1].Title != "Fine" {
+		t.Errorf("Wrong doc after the partial one. Got %+v", docs[1])
+	}
+}
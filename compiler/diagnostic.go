@@ -0,0 +1,67 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found at a position in the source.
+type Diagnostic struct {
+	Pos      tokenizer.Position `json:"pos"`
+	Severity Severity           `json:"severity"`
+	Message  string             `json:"message"`
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%v: %v", d.Pos, d.Message)
+}
+
+// Diagnostics is returned by Compile when any doc failed to compile.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	msgs := make([]string, 0, len(d))
+	for _, diag := range d {
+		msgs = append(msgs, diag.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// HasErrors reports whether any diagnostic is an error rather than a
+// warning.
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// AsDiagnostics converts a tokenizer or compiler error into diagnostics.
+// Errors without a position are reported at the start of the source.
+func AsDiagnostics(err error) Diagnostics {
+	var diags Diagnostics
+	if errors.As(err, &diags) {
+		return diags
+	}
+	var diag Diagnostic
+	if errors.As(err, &diag) {
+		return Diagnostics{diag}
+	}
+	var tokErr *tokenizer.Error
+	if errors.As(err, &tokErr) {
+		return Diagnostics{{Pos: tokErr.Pos, Severity: SeverityError, Message: tokErr.Msg}}
+	}
+	return Diagnostics{{Pos: tokenizer.Position{Line: 1, Column: 1}, Severity: SeverityError, Message: err.Error()}}
+}
@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// Policy is how a query may be executed. Zero values mean no limit.
+type Policy struct {
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Cache    time.Duration `json:"cache,omitempty"`
+	ReadOnly bool          `json:"readOnly,omitempty"`
+	MaxRows  int           `json:"maxRows,omitempty"`
+}
+
+func (c *Compiler) compilePolicy(t tokenizer.Tokener, p *Policy) error {
+	value, err := c.next()
+	if err != nil {
+		return err
+	}
+	if _, ok := value.(tokenizer.BareWord); !ok {
+		return c.expected(value, "a value after @"+t.Original())
+	}
+	switch t.(type) {
+	case tokenizer.Timeout, tokenizer.Cache:
+		d, err := time.ParseDuration(value.Original())
+		if err != nil || d <= 0 {
+			return c.errorf(value, "@%v must be a positive duration like 30s, got %q", t.Original(), value.Original())
+		}
+		if _, ok := t.(tokenizer.Timeout); ok {
+			p.Timeout = d
+		} else {
+			p.Cache = d
+		}
+	case tokenizer.MaxRows:
+		n, err := strconv.Atoi(value.Original())
+		if err != nil || n <= 0 {
+			return c.errorf(value, "@maxrows must be a positive integer, got %q", value.Original())
+		}
+		p.MaxRows = n
+	}
+	return nil
+}
+
+var readKeywords = map[string]bool{
+	"select": true, "with": true, "values": true, "explain": true, "show": true, "describe": true,
+}
+
+var writeKeywords = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true, "upsert": true,
+	"create": true, "drop": true, "alter": true, "truncate": true, "grant": true,
+	"revoke": true, "attach": true, "detach": true, "into": true,
+}
+
+// IsReadOnly reports whether a statement only reads: it starts with a
+// reading keyword and mentions no writing keyword outside of literals,
+// quoted identifiers and comments. Being a keyword check it can't see
+// writes hidden in functions and the like, so it only catches the obvious
+// ones early; runners enforce @readonly with a read-only transaction.
+func IsReadOnly(sql string) bool {
+	words := keywords(sql)
+	if len(words) == 0 || !readKeywords[words[0]] {
+		return false
+	}
+	for _, w := range words {
+		if writeKeywords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+func keywords(sql string) []string {
+	words := make([]string, 0)
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			flush()
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				return words
+			}
+			i += end + 1
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			flush()
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return words
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			flush()
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+			i += end + 3
+		case c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			word.WriteByte(c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,21 @@
+package compiler
+
+import "testing"
+
+func TestIsReadOnly(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT * FROM orders":                                              true,
+		"with o as (select 1) select * from o":                              true,
+		"SELECT 'delete' AS word -- drop it\n FROM t":                       true,
+		`SELECT "update" FROM t /* insert */`:                               true,
+		"DELETE FROM orders":                                                false,
+		"WITH gone AS (DELETE FROM orders RETURNING id) SELECT * FROM gone": false,
+		"SELECT * INTO copy FROM orders":                                    false,
+		"PRAGMA table_info(orders)":                                         false,
+	}
+	for sql, want := range cases {
+		if got := IsReadOnly(sql); got != want {
+			t.Errorf("Wrong answer for %q. Got %v want %v", sql, got, want)
+		}
+	}
+}
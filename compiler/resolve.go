@@ -0,0 +1,316 @@
+package compiler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Resolve expands shared definitions. Docs with @define are definitions
+// rather than queries and are dropped. Each query gains the params and
+// columns of the definitions it @includes, and every use:name param or
+// column is replaced by the one of that name in the definitions the doc
+// includes, or by use:definition.name in any definition. Definitions may
+// include one another, each keeping its own names. Cycles, unknown or
+// ambiguous names and conflicts are reported as Diagnostics, the docs they
+// concern marked Incomplete. docs themselves are left as they are.
+func Resolve(docs []QueryDoc) ([]QueryDoc, error) {
+	docs = slices.Clone(docs)
+	for i := range docs {
+		docs[i].Params = slices.Clone(docs[i].Params)
+		docs[i].Output.Columns = slices.Clone(docs[i].Output.Columns)
+		docs[i].Diagnostics = slices.Clone(docs[i].Diagnostics)
+	}
+	r := resolver{
+		defs:    make(map[string]*QueryDoc),
+		origins: make(map[*QueryDoc]origins),
+		state:   make(map[string]int),
+	}
+	for i := range docs {
+		q := &docs[i]
+		if q.Define == "" {
+			continue
+		}
+		if _, ok := r.defs[q.Define]; ok {
+			r.errorf(q, "definition %v is defined twice", q.Define)
+			continue
+		}
+		r.defs[q.Define] = q
+	}
+	for _, name := range r.order(docs) {
+		r.resolve(r.defs[name])
+	}
+	resolved := make([]QueryDoc, 0, len(docs))
+	for i := range docs {
+		q := &docs[i]
+		if q.Define != "" {
+			continue
+		}
+		r.expand(q, nil)
+		resolved = append(resolved, *q)
+	}
+	if len(r.diags) > 0 {
+		return resolved, r.diags
+	}
+	return resolved, nil
+}
+
+const (
+	unvisited = iota
+	visiting
+	done
+)
+
+// origins map the names of a doc's params and columns to the definition
+// declaring each, "" for those a query declares itself.
+type origins struct {
+	params  map[string]string
+	columns map[string]string
+}
+
+type resolver struct {
+	defs map[string]*QueryDoc
+	// origins are those of each expanded doc.
+	origins map[*QueryDoc]origins
+	// state tracks each definition through resolve, and stack the
+	// definitions being resolved, to report cycles.
+	state map[string]int
+	stack []string
+	diags Diagnostics
+}
+
+func (r *resolver) errorf(q *QueryDoc, format string, args ...interface{}) {
+	d := Diagnostic{Pos: q.Pos, Severity: SeverityError, Message: fmt.Sprintf(format, args...)}
+	q.Incomplete = true
+	q.Diagnostics = append(q.Diagnostics, d)
+	r.diags = append(r.diags, d)
+}
+
+// order lists the definitions in the order they appear in docs, skipping
+// duplicates.
+func (r *resolver) order(docs []QueryDoc) []string {
+	names := make([]string, 0, len(r.defs))
+	for i := range docs {
+		if def, ok := r.defs[docs[i].Define]; ok && def == &docs[i] {
+			names = append(names, docs[i].Define)
+		}
+	}
+	return names
+}
+
+// resolve expands a definition after the definitions it depends on,
+// reporting a cycle if it's reached again while they are expanded.
+func (r *resolver) resolve(def *QueryDoc) {
+	switch r.state[def.Define] {
+	case done:
+		return
+	case visiting:
+		i := len(r.stack) - 1
+		for r.stack[i] != def.Define {
+			i--
+		}
+		cycle := append(append([]string(nil), r.stack[i:]...), def.Define)
+		r.errorf(def, "include cycle: %v", strings.Join(cycle, " -> "))
+		return
+	}
+	r.state[def.Define] = visiting
+	r.stack = append(r.stack, def.Define)
+	r.expand(def, r.resolve)
+	r.stack = r.stack[:len(r.stack)-1]
+	r.state[def.Define] = done
+}
+
+// dependencies are the definitions q includes or uses a name of.
+func (r *resolver) dependencies(q *QueryDoc) []*QueryDoc {
+	deps := make([]*QueryDoc, 0)
+	add := func(name string) {
+		if def, ok := r.defs[name]; ok {
+			deps = append(deps, def)
+		}
+	}
+	for _, name := range q.Includes {
+		add(name)
+	}
+	for _, p := range q.Params {
+		if name, _, ok := strings.Cut(p.Use, "."); ok {
+			add(name)
+		}
+	}
+	for _, c := range q.Output.Columns {
+		if name, _, ok := strings.Cut(c.Use, "."); ok {
+			add(name)
+		}
+	}
+	return deps
+}
+
+// expand replaces q's use:name params and columns and adds those of the
+// definitions it includes, calling before on each definition it depends
+// on first.
+func (r *resolver) expand(q *QueryDoc, before func(*QueryDoc)) {
+	if before != nil {
+		for _, def := range r.dependencies(q) {
+			before(def)
+		}
+	}
+	// Names q has from two definitions, or from a definition and itself,
+	// conflict. One reached through two includes doesn't.
+	have := origins{params: make(map[string]string), columns: make(map[string]string)}
+	r.origins[q] = have
+	for i, p := range q.Params {
+		origin := q.Define
+		if p.Use != "" {
+			q.Params[i], origin = r.useParam(q, p)
+		}
+		have.params[q.Params[i].ProperName] = origin
+	}
+	for i, c := range q.Output.Columns {
+		origin := q.Define
+		if c.Use != "" {
+			q.Output.Columns[i], origin = r.useColumn(q, c)
+		}
+		have.columns[q.Output.Columns[i].ProperName] = origin
+	}
+	for _, name := range q.Includes {
+		def, ok := r.defs[name]
+		if !ok {
+			r.errorf(q, "%v includes unknown definition %v", label(q), name)
+			continue
+		}
+		if r.state[name] == visiting {
+			// Part of a cycle, already reported.
+			continue
+		}
+		from := r.origins[def]
+		for _, p := range def.Params {
+			origin := from.params[p.ProperName]
+			if other, ok := have.params[p.ProperName]; ok {
+				if other != origin {
+					r.errorf(q, "%v declares param %v, which %v also does", label(q), p.ProperName, name)
+				}
+				continue
+			}
+			have.params[p.ProperName] = origin
+			q.Params = append(q.Params, p)
+		}
+		for _, c := range def.Output.Columns {
+			origin := from.columns[c.ProperName]
+			if other, ok := have.columns[c.ProperName]; ok {
+				if other != origin {
+					r.errorf(q, "%v declares column %v, which %v also does", label(q), c.ProperName, name)
+				}
+				continue
+			}
+			have.columns[c.ProperName] = origin
+			q.Output.Columns = append(q.Output.Columns, c)
+		}
+		if q.Output.Title == "" && q.Output.Description == "" {
+			q.Output.Title, q.Output.Description = def.Output.Title, def.Output.Description
+			q.Output.DescriptionHTML, q.Output.Markup = def.Output.DescriptionHTML, def.Output.Markup
+		}
+	}
+}
+
+// scope lists the definitions a use:name reference from q may mean: the
+// one it qualifies, or else those q includes. Definitions in a cycle
+// being resolved are left out.
+func (r *resolver) scope(q *QueryDoc, use string) (string, []*QueryDoc) {
+	names := q.Includes
+	if def, name, ok := strings.Cut(use, "."); ok {
+		names, use = []string{def}, name
+	}
+	defs := make([]*QueryDoc, 0, len(names))
+	for _, name := range names {
+		if def, ok := r.defs[name]; ok && r.state[name] != visiting {
+			defs = append(defs, def)
+		}
+	}
+	return use, defs
+}
+
+// useParam is the param p uses, keeping anything p adds to it, and the
+// definition declaring it.
+func (r *resolver) useParam(q *QueryDoc, p Param) (Param, string) {
+	name, defs := r.scope(q, p.Use)
+	var used *Param
+	origin := ""
+	for _, def := range defs {
+		for i := range def.Params {
+			if def.Params[i].ProperName != name {
+				continue
+			}
+			from := r.origins[def].params[name]
+			if used != nil && origin != from {
+				r.errorf(q, "%v uses param %v, which is in both %v and %v", label(q), name, origin, from)
+				return p, ""
+			}
+			used, origin = &def.Params[i], from
+		}
+	}
+	if used == nil {
+		r.errorf(q, "%v uses unknown param %v", label(q), p.Use)
+		return p, ""
+	}
+	u := *used
+	u.Use = p.Use
+	u.Required = u.Required || p.Required
+	// The reference's @default and @enum were only checked as strings,
+	// not knowing the type.
+	if p.Default != nil {
+		if _, err := u.Type.Value(*p.Default); err != nil {
+			r.errorf(q, "bad default for param %v: %v", u.ProperName, err)
+		} else {
+			u.Default = p.Default
+		}
+	}
+	enum := make([]string, 0, len(p.Enum))
+	for _, value := range p.Enum {
+		if _, err := u.Type.Value(value); err != nil {
+			r.errorf(q, "bad enum value for param %v: %v", u.ProperName, err)
+			continue
+		}
+		enum = append(enum, value)
+	}
+	if len(enum) > 0 {
+		u.Enum = enum
+	}
+	return u, origin
+}
+
+func (r *resolver) useColumn(q *QueryDoc, c Column) (Column, string) {
+	name, defs := r.scope(q, c.Use)
+	var used *Column
+	origin := ""
+	for _, def := range defs {
+		for i := range def.Output.Columns {
+			if def.Output.Columns[i].ProperName != name {
+				continue
+			}
+			from := r.origins[def].columns[name]
+			if used != nil && origin != from {
+				r.errorf(q, "%v uses column %v, which is in both %v and %v", label(q), name, origin, from)
+				return c, ""
+			}
+			used, origin = &def.Output.Columns[i], from
+		}
+	}
+	if used == nil {
+		r.errorf(q, "%v uses unknown column %v", label(q), c.Use)
+		return c, ""
+	}
+	u := *used
+	u.Use = c.Use
+	u.Sensitive = u.Sensitive || c.Sensitive
+	return u, origin
+}
+
+// label names q in diagnostics.
+func label(q *QueryDoc) string {
+	if q.Define != "" {
+		return "definition " + q.Define
+	}
+	if q.Name != "" {
+		return "query " + q.Name
+	}
+	return "query"
+}
@@ -0,0 +1,157 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+func compileSource(t *testing.T, src string) []QueryDoc {
+	tok := tokenizer.NewTokenizer(strings.NewReader(src))
+	if err := tok.Tokenize(); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := Compile(tok.Tokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return docs
+}
+
+const sharedSource = `/**
+@define dates
+@param start_date date "Start" "The first day."
+@param end_date date "End" "The last day."
+*/
+
+/**
+@define users
+@include dates
+@table {
+	@title "Users"
+	@column user_id integer "User" "The user's ID."
+}
+*/
+
+/**
+@name signups
+@include users
+@param use:start_date
+@required
+*/
+SELECT user_id FROM signups WHERE day BETWEEN ${start_date} AND ${end_date};
+
+/**
+@name logins
+@param use:dates.end_date
+@table {
+	@column use:users.user_id
+	@column device "Device" "What they logged in from."
+}
+*/
+SELECT user_id, device FROM logins WHERE day <= ${end_date};`
+
+func TestResolve(t *testing.T) {
+	docs, err := Resolve(compileSource(t, sharedSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Definitions weren't dropped. Got %v docs want 2", len(docs))
+	}
+	signups := docs[0]
+	names := make([]string, 0)
+	for _, p := range signups.Params {
+		names = append(names, p.ProperName)
+	}
+	if strings.Join(names, " ") != "start_date end_date" {
+		t.Errorf("Wrong params. Got %v want start_date end_date", names)
+	}
+	start := signups.Params[0]
+	if start.Use != "start_date" || start.Type != Date || start.Blurb != "Start" || !start.Required {
+		t.Errorf("Wrong used param. Got %+v", start)
+	}
+	if signups.Output.Title != "Users" || len(signups.Output.Columns) != 1 || signups.Output.Columns[0].Type != Integer {
+		t.Errorf("Wrong included table. Got %+v", signups.Output)
+	}
+	logins := docs[1]
+	if logins.Params[0].Description != "The last day." {
+		t.Errorf("Wrong used param. Got %+v", logins.Params[0])
+	}
+	if c := logins.Output.Columns[0]; c.ProperName != "user_id" || c.Blurb != "User" || c.Use != "users.user_id" {
+		t.Errorf("Wrong used column. Got %+v", c)
+	}
+}
+
+const scopedSource = `/**
+@define users
+@table {
+	@column id integer "User" "The user's ID."
+}
+*/
+
+/**
+@define orders
+@table {
+	@column id integer "Order" "The order's ID."
+}
+*/
+
+/**
+@name user_ids
+@include users
+@table {
+	@column use:id
+}
+*/
+SELECT id FROM users;
+
+/**
+@name order_ids
+@table {
+	@column use:orders.id
+}
+*/
+SELECT id FROM orders;`
+
+func TestResolveScopes(t *testing.T) {
+	docs, err := Resolve(compileSource(t, scopedSource))
+	if err != nil {
+		t.Fatalf("Definitions sharing a name conflicted. Got %v", err)
+	}
+	if c := docs[0].Output.Columns[0]; c.Blurb != "User" {
+		t.Errorf("Wrong column through @include. Got %+v", c)
+	}
+	if c := docs[1].Output.Columns[0]; c.ProperName != "id" || c.Blurb != "Order" {
+		t.Errorf("Wrong qualified column. Got %+v", c)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	inputs := map[string]string{
+		"/**\n@define a\n@include b\n*/\n/**\n@define b\n@include a\n*/\n":                                                                                        "include cycle: a -> b -> a",
+		"/**\n@name q\n@include nothing\n*/\nSELECT 1;":                                                                                                           "query q includes unknown definition nothing",
+		"/**\n@name q\n@param use:nothing\n*/\nSELECT 1;":                                                                                                         "query q uses unknown param nothing",
+		"/**\n@define a\n@param id int \"ID\" \"\"\n*/\n/**\n@name q\n@param use:id\n*/\nSELECT 1;":                                                               "query q uses unknown param id",
+		"/**\n@define a\n@param id int \"ID\" \"\"\n*/\n/**\n@name q\n@param use:a.id\n@default \"soon\"\n*/\nSELECT 1;":                                          "bad default for param id: strconv.ParseInt: parsing \"soon\": invalid syntax",
+		"/**\n@define a\n@param id int \"ID\" \"\"\n*/\n/**\n@name q\n@param use:a.id\n@enum \"1\" \"x\"\n*/\nSELECT 1;":                                          "bad enum value for param id: strconv.ParseInt: parsing \"x\": invalid syntax",
+		"/**\n@define a\n@param id int \"ID\" \"\"\n*/\n/**\n@name q\n@param use:b.id\n*/\nSELECT 1;":                                                             "query q uses unknown param b.id",
+		"/**\n@define a\n@param id int \"A\" \"\"\n*/\n/**\n@define b\n@param id int \"B\" \"\"\n*/\n/**\n@name q\n@include a\n@include b\n*/\nSELECT 1;":         "query q declares param id, which b also does",
+		"/**\n@define a\n@param id int \"A\" \"\"\n*/\n/**\n@define b\n@param id int \"B\" \"\"\n*/\n/**\n@define c\n@include a\n@include b\n@param use:id\n*/\n": "definition c uses param id, which is in both a and b",
+		"/**\n@define a\n*/\n/**\n@define a\n*/\n":                                                                                                                "definition a is defined twice",
+		"/**\n@define a\n@param id int \"ID\" \"\"\n*/\n/**\n@name q\n@include a\n@param id int \"ID\" \"\"\n*/\nSELECT 1;":                                       "query q declares param id, which a also does",
+	}
+	for input, want := range inputs {
+		docs, err := Resolve(compileSource(t, input))
+		diags := AsDiagnostics(err)
+		if err == nil || diags[0].Message != want {
+			t.Errorf("Wrong error for %q. Got %v want %v", input, err, want)
+		}
+		for _, q := range docs {
+			if len(q.Diagnostics) > 0 && !q.Incomplete {
+				t.Errorf("Doc with diagnostics wasn't marked incomplete. Got %+v", q)
+			}
+		}
+	}
+}
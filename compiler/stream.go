@@ -0,0 +1,72 @@
+package compiler
+
+import (
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// Source hands out tokens one at a time, like *tokenizer.Tokenizer.
+type Source interface {
+	Next() (tokenizer.Tokener, error)
+}
+
+// Stream compiles docs as their tokens arrive, holding only the tokens of
+// the doc being compiled.
+type Stream struct {
+	src Source
+	err error
+}
+
+func NewStream(src Source) *Stream {
+	return &Stream{src: src}
+}
+
+// Next compiles the next doc. A doc that fails to compile is returned,
+// Incomplete, with Diagnostics, and the stream carries on with the doc
+// after it. Any other error comes from the source and ends the stream, as
+// does io.EOF.
+func (s *Stream) Next() (QueryDoc, error) {
+	if s.err != nil {
+		return QueryDoc{}, s.err
+	}
+	tokens := make([]tokenizer.Tokener, 0)
+	for {
+		tok, err := s.src.Next()
+		if err != nil {
+			s.err = err
+			if err != io.EOF || len(tokens) == 0 {
+				return QueryDoc{}, err
+			}
+			break
+		}
+		tokens = append(tokens, tok)
+		if _, ok := tok.(tokenizer.CloseDoc); ok {
+			break
+		}
+	}
+	docs, err := Compile(tokens)
+	if len(docs) == 0 {
+		return QueryDoc{}, err
+	}
+	return docs[0], err
+}
+
+// All yields the docs Next returns, carrying on past docs that fail to
+// compile and ending with the first error from the source.
+func (s *Stream) All() iter.Seq2[QueryDoc, error] {
+	return func(yield func(QueryDoc, error) bool) {
+		for {
+			doc, err := s.Next()
+			if err == io.EOF {
+				return
+			}
+			var diags Diagnostics
+			if !yield(doc, err) || (err != nil && !errors.As(err, &diags)) {
+				return
+			}
+		}
+	}
+}
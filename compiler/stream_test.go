@@ -0,0 +1,45 @@
+package compiler
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+const streamSource = `/**
+@title "First"
+*/
+SELECT 1;
+/**
+@title "Broken"
+@default "x"
+*/
+SELECT 2;
+/**
+@title "Third"
+*/
+SELECT 3;`
+
+func TestStream(t *testing.T) {
+	s := NewStream(tokenizer.NewTokenizer(strings.NewReader(streamSource)))
+	doc, err := s.Next()
+	if err != nil || doc.Title != "First" || doc.SQL != "SELECT 1" {
+		t.Errorf("Wrong first doc. Got %v, %v", doc.Title, err)
+	}
+	doc, err = s.Next()
+	if diags := AsDiagnostics(err); err == nil || diags[0].Pos.Line != 7 {
+		t.Errorf("Expected diagnostics for the second doc. Got %v", err)
+	}
+	if !doc.Incomplete || doc.Title != "Broken" {
+		t.Errorf("Wrong partial second doc. Got %v, incomplete %v", doc.Title, doc.Incomplete)
+	}
+	doc, err = s.Next()
+	if err != nil || doc.Name != "third" {
+		t.Errorf("Wrong third doc. Got %v, %v", doc.Name, err)
+	}
+	if _, err = s.Next(); err != io.EOF {
+		t.Errorf("Wrong error at the end. Got %v want %v", err, io.EOF)
+	}
+}
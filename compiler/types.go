@@ -0,0 +1,71 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the declared type of a param or column.
+type Type string
+
+const (
+	String   Type = "string"
+	Integer  Type = "integer"
+	Number   Type = "number"
+	Boolean  Type = "boolean"
+	Date     Type = "date"
+	DateTime Type = "datetime"
+)
+
+var typeNames = map[string]Type{
+	"string":    String,
+	"text":      String,
+	"varchar":   String,
+	"char":      String,
+	"integer":   Integer,
+	"int":       Integer,
+	"bigint":    Integer,
+	"smallint":  Integer,
+	"number":    Number,
+	"numeric":   Number,
+	"decimal":   Number,
+	"float":     Number,
+	"real":      Number,
+	"double":    Number,
+	"boolean":   Boolean,
+	"bool":      Boolean,
+	"date":      Date,
+	"datetime":  DateTime,
+	"timestamp": DateTime,
+}
+
+// ParseType resolves a type name, or one of its SQL flavoured aliases.
+func ParseType(name string) (Type, error) {
+	typ, ok := typeNames[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown type %q", name)
+	}
+	return typ, nil
+}
+
+// Value parses s as a literal of type t. Dates and datetimes are validated
+// but stay strings.
+func (t Type) Value(s string) (interface{}, error) {
+	switch t {
+	case Integer:
+		return strconv.ParseInt(s, 10, 64)
+	case Number:
+		return strconv.ParseFloat(s, 64)
+	case Boolean:
+		return strconv.ParseBool(s)
+	case Date:
+		_, err := time.Parse("2006-01-02", s)
+		return s, err
+	case DateTime:
+		_, err := time.Parse(time.RFC3339, s)
+		return s, err
+	}
+	return s, nil
+}
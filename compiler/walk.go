@@ -0,0 +1,97 @@
+package compiler
+
+// Action is what an enter hook tells Walk to do with a node.
+type Action int
+
+const (
+	// Continue walks the node's children and then leaves it.
+	Continue Action = iota
+	// SkipChildren leaves the node without walking its children.
+	SkipChildren
+	// Remove drops the node without walking its children or leaving it.
+	// A removed table is replaced by an empty one.
+	Remove
+)
+
+// Visitor has hooks for entering and leaving each kind of node Walk comes
+// to. Hooks get pointers into the tree and may rewrite nodes in place.
+type Visitor interface {
+	EnterDoc(q *QueryDoc) Action
+	LeaveDoc(q *QueryDoc)
+	EnterParam(p *Param) Action
+	LeaveParam(p *Param)
+	EnterTable(t *Table) Action
+	LeaveTable(t *Table)
+	EnterColumn(c *Column) Action
+	LeaveColumn(c *Column)
+}
+
+// NopVisitor does nothing. Embed it to implement only the hooks you need.
+type NopVisitor struct{}
+
+func (NopVisitor) EnterDoc(*QueryDoc) Action  { return Continue }
+func (NopVisitor) LeaveDoc(*QueryDoc)         {}
+func (NopVisitor) EnterParam(*Param) Action   { return Continue }
+func (NopVisitor) LeaveParam(*Param)          {}
+func (NopVisitor) EnterTable(*Table) Action   { return Continue }
+func (NopVisitor) LeaveTable(*Table)          {}
+func (NopVisitor) EnterColumn(*Column) Action { return Continue }
+func (NopVisitor) LeaveColumn(*Column)        {}
+
+// Walk visits docs depth first: each doc, its params, then its output
+// table and its columns. It rewrites docs in place and returns them less
+// any that were removed.
+func Walk(v Visitor, docs []QueryDoc) []QueryDoc {
+	kept := docs[:0]
+	for i := range docs {
+		if WalkDoc(v, &docs[i]) {
+			kept = append(kept, docs[i])
+		}
+	}
+	return kept
+}
+
+// WalkDoc walks a single doc like Walk, reporting whether it was kept.
+func WalkDoc(v Visitor, q *QueryDoc) bool {
+	switch v.EnterDoc(q) {
+	case Remove:
+		return false
+	case SkipChildren:
+		v.LeaveDoc(q)
+		return true
+	}
+	params := q.Params[:0]
+	for i := range q.Params {
+		p := &q.Params[i]
+		if v.EnterParam(p) == Remove {
+			continue
+		}
+		v.LeaveParam(p)
+		params = append(params, *p)
+	}
+	q.Params = params
+	switch v.EnterTable(&q.Output) {
+	case Remove:
+		q.Output = NewTable()
+	case SkipChildren:
+		v.LeaveTable(&q.Output)
+	default:
+		walkColumns(v, &q.Output)
+		v.LeaveTable(&q.Output)
+	}
+	v.LeaveDoc(q)
+	return true
+}
+
+func walkColumns(v Visitor, t *Table) {
+	columns := t.Columns[:0]
+	for i := range t.Columns {
+		c := &t.Columns[i]
+		if v.EnterColumn(c) == Remove {
+			continue
+		}
+		v.LeaveColumn(c)
+		columns = append(columns, *c)
+	}
+	t.Columns = columns
+}
@@ -0,0 +1,69 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	NopVisitor
+	visits []string
+}
+
+func (r *recorder) EnterDoc(q *QueryDoc) Action {
+	r.visits = append(r.visits, "doc "+q.Name)
+	if q.Name == "skipped" {
+		return SkipChildren
+	}
+	if q.Name == "removed" {
+		return Remove
+	}
+	return Continue
+}
+
+func (r *recorder) LeaveDoc(q *QueryDoc) {
+	r.visits = append(r.visits, "/doc "+q.Name)
+}
+
+func (r *recorder) EnterParam(p *Param) Action {
+	r.visits = append(r.visits, "param "+p.ProperName)
+	if p.ProperName == "secret" {
+		return Remove
+	}
+	return Continue
+}
+
+func (r *recorder) EnterTable(t *Table) Action {
+	r.visits = append(r.visits, "table")
+	return Continue
+}
+
+func (r *recorder) EnterColumn(c *Column) Action {
+	r.visits = append(r.visits, "column "+c.ProperName)
+	c.ProperName = strings.ToUpper(c.ProperName)
+	return Continue
+}
+
+func TestWalk(t *testing.T) {
+	docs := []QueryDoc{
+		{Name: "kept", Params: []Param{{ProperName: "id"}, {ProperName: "secret"}}, Output: Table{Columns: []Column{{ProperName: "name"}}}},
+		{Name: "skipped", Params: []Param{{ProperName: "id"}}},
+		{Name: "removed"},
+	}
+	r := &recorder{}
+	docs = Walk(r, docs)
+	got := strings.Join(r.visits, ", ")
+	want := "doc kept, param id, param secret, table, column name, /doc kept, doc skipped, /doc skipped, doc removed"
+	if got != want {
+		t.Errorf("Wrong visits. Got %v want %v", got, want)
+	}
+	if len(docs) != 2 || docs[1].Name != "skipped" {
+		t.Fatalf("Wrong docs kept. Got %+v", docs)
+	}
+	if len(docs[0].Params) != 1 || docs[0].Params[0].ProperName != "id" {
+		t.Errorf("Wrong params kept. Got %+v", docs[0].Params)
+	}
+	if docs[0].Output.Columns[0].ProperName != "NAME" {
+		t.Errorf("Column wasn't rewritten. Got %v want NAME", docs[0].Output.Columns[0].ProperName)
+	}
+}
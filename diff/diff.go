@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+type Kind string
+
+const (
+	QueryRemoved     Kind = "query removed"
+	QueryAdded       Kind = "query added"
+	ParamRemoved     Kind = "param removed"
+	ParamRenamed     Kind = "param renamed"
+	ParamRetyped     Kind = "param retyped"
+	ParamAdded       Kind = "param added"
+	ParamNowRequired Kind = "param now required"
+	ColumnRemoved    Kind = "column removed"
+	ColumnRetyped    Kind = "column retyped"
+	ColumnAdded      Kind = "column added"
+	Cosmetic         Kind = "cosmetic"
+)
+
+// Change is one difference between two versions of a query.
+type Change struct {
+	Query    string `json:"query"`
+	Kind     Kind   `json:"kind"`
+	Breaking bool   `json:"breaking"`
+	Detail   string `json:"detail"`
+}
+
+func (c Change) String() string {
+	label := "compatible"
+	if c.Breaking {
+		label = "BREAKING"
+	}
+	return fmt.Sprintf("%s %s: %s: %s", label, c.Query, c.Kind, c.Detail)
+}
+
+// Breaking reports whether any change breaks existing callers.
+func Breaking(changes []Change) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Catalogs compares every query in old against new by name.
+func Catalogs(old, new *catalog.Catalog) []Change {
+	changes := make([]Change, 0)
+	for _, o := range old.Docs {
+		n, ok := new.Lookup(o.Name)
+		if !ok {
+			changes = append(changes, Change{o.Name, QueryRemoved, true, "no longer in the catalog"})
+			continue
+		}
+		changes = append(changes, Queries(o, n)...)
+	}
+	for _, n := range new.Docs {
+		if _, ok := old.Lookup(n.Name); !ok {
+			changes = append(changes, Change{n.Name, QueryAdded, false, "new query"})
+		}
+	}
+	return changes
+}
+
+// Queries compares two versions of the same query.
+func Queries(old, new compiler.QueryDoc) []Change {
+	d := differ{query: new.Name, changes: make([]Change, 0)}
+	d.params(old.Params, new.Params)
+	d.columns(old.Output.Columns, new.Output.Columns)
+	if old.Title != new.Title {
+		d.add(Cosmetic, false, "title changed from %q to %q", old.Title, new.Title)
+	}
+	if old.Description != new.Description {
+		d.add(Cosmetic, false, "description changed")
+	}
+	if old.Output.Title != new.Output.Title || old.Output.Description != new.Output.Description {
+		d.add(Cosmetic, false, "output table docs changed")
+	}
+	return d.changes
+}
+
+type differ struct {
+	query   string
+	changes []Change
+}
+
+func (d *differ) add(kind Kind, breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{d.query, kind, breaking, fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) params(old, new []compiler.Param) {
+	removed := make([]compiler.Param, 0)
+	for _, o := range old {
+		n, ok := findParam(new, o.ProperName)
+		if !ok {
+			removed = append(removed, o)
+			continue
+		}
+		if o.Type != n.Type {
+			d.add(ParamRetyped, true, "%v changed from %v to %v", o.ProperName, o.Type, n.Type)
+		}
+		if !mandatory(o) && mandatory(n) {
+			d.add(ParamNowRequired, true, "%v is now required", o.ProperName)
+		}
+		if o.Blurb != n.Blurb || o.Description != n.Description {
+			d.add(Cosmetic, false, "%v docs changed", o.ProperName)
+		}
+	}
+	added := make([]compiler.Param, 0)
+	for _, n := range new {
+		if _, ok := findParam(old, n.ProperName); !ok {
+			added = append(added, n)
+		}
+	}
+	// A param that went away while one of the same type appeared in its
+	// place is taken to be renamed.
+	for _, o := range removed {
+		renamed := false
+		for i, n := range added {
+			if n.Type == o.Type && index(old, o.ProperName) == index(new, n.ProperName) {
+				d.add(ParamRenamed, true, "%v renamed to %v", o.ProperName, n.ProperName)
+				added = append(added[:i], added[i+1:]...)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			d.add(ParamRemoved, true, "%v removed", o.ProperName)
+		}
+	}
+	for _, n := range added {
+		if mandatory(n) {
+			d.add(ParamNowRequired, true, "new param %v is required", n.ProperName)
+		} else {
+			d.add(ParamAdded, false, "new optional param %v", n.ProperName)
+		}
+	}
+}
+
+func (d *differ) columns(old, new []compiler.Column) {
+	for _, o := range old {
+		n, ok := findColumn(new, o.ProperName)
+		switch {
+		case !ok:
+			d.add(ColumnRemoved, true, "%v removed", o.ProperName)
+		case o.Type != n.Type:
+			d.add(ColumnRetyped, true, "%v changed from %v to %v", o.ProperName, o.Type, n.Type)
+		case o.Blurb != n.Blurb || o.Description != n.Description:
+			d.add(Cosmetic, false, "%v docs changed", o.ProperName)
+		}
+	}
+	for _, n := range new {
+		if _, ok := findColumn(old, n.ProperName); !ok {
+			d.add(ColumnAdded, false, "new column %v", n.ProperName)
+		}
+	}
+}
+
+// mandatory params must be supplied by every caller.
+func mandatory(p compiler.Param) bool {
+	return p.Required && p.Default == nil
+}
+
+func findParam(params []compiler.Param, name string) (compiler.Param, bool) {
+	i := index(params, name)
+	if i < 0 {
+		return compiler.Param{}, false
+	}
+	return params[i], true
+}
+
+func index(params []compiler.Param, name string) int {
+	for i, p := range params {
+		if p.ProperName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func findColumn(columns []compiler.Column, name string) (compiler.Column, bool) {
+	for _, c := range columns {
+		if c.ProperName == name {
+			return c, true
+		}
+	}
+	return compiler.Column{}, false
+}
@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var ten = "10"
+
+func TestCatalogs(t *testing.T) {
+	old := catalog.New([]compiler.QueryDoc{
+		{
+			Name:        "orders",
+			Description: "Orders.",
+			Params: []compiler.Param{
+				{ProperName: "status", Type: compiler.String},
+				{ProperName: "since", Type: compiler.Date},
+				{ProperName: "limit", Type: compiler.Integer},
+				{ProperName: "team", Type: compiler.Integer},
+			},
+			Output: compiler.Table{Columns: []compiler.Column{
+				{ProperName: "id", Type: compiler.Integer},
+				{ProperName: "total", Type: compiler.Number},
+				{ProperName: "note", Type: compiler.String},
+			}},
+		},
+		{Name: "gone"},
+	})
+	new := catalog.New([]compiler.QueryDoc{
+		{
+			Name:        "orders",
+			Description: "All orders.",
+			Params: []compiler.Param{
+				{ProperName: "status", Type: compiler.String, Required: true},
+				{ProperName: "after", Type: compiler.Date},
+				{ProperName: "limit", Type: compiler.String},
+				{ProperName: "page", Type: compiler.String, Required: true, Default: &ten},
+			},
+			Output: compiler.Table{Columns: []compiler.Column{
+				{ProperName: "id", Type: compiler.String},
+				{ProperName: "note", Type: compiler.String, Blurb: "Note"},
+				{ProperName: "created", Type: compiler.Date},
+			}},
+		},
+		{Name: "fresh"},
+	})
+	want := []Change{
+		{"orders", ParamNowRequired, true, "status is now required"},
+		{"orders", ParamRetyped, true, "limit changed from integer to string"},
+		{"orders", ParamRenamed, true, "since renamed to after"},
+		{"orders", ParamRemoved, true, "team removed"},
+		{"orders", ParamAdded, false, "new optional param page"},
+		{"orders", ColumnRetyped, true, "id changed from integer to string"},
+		{"orders", ColumnRemoved, true, "total removed"},
+		{"orders", Cosmetic, false, "note docs changed"},
+		{"orders", ColumnAdded, false, "new column created"},
+		{"orders", Cosmetic, false, "description changed"},
+		{"gone", QueryRemoved, true, "no longer in the catalog"},
+		{"fresh", QueryAdded, false, "new query"},
+	}
+	got := Catalogs(old, new)
+	if len(got) != len(want) {
+		t.Fatalf("Wrong changes. Got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Wrong change at %v. Got %v want %v", i, got[i], want[i])
+		}
+	}
+	if !Breaking(got) {
+		t.Errorf("Expected breaking changes")
+	}
+}
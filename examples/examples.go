@@ -0,0 +1,191 @@
+package examples
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/runner"
+)
+
+// Failure is one way an example's rows differ from what it expects.
+type Failure struct {
+	Query   string `json:"query"`
+	Example string `json:"example"`
+	Message string `json:"message"`
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s: example %q: %s", f.Query, f.Example, f.Message)
+}
+
+// Expected reads the CSV an example expects, header first. Files are
+// relative to the query's source.
+func Expected(q compiler.QueryDoc, ex compiler.Example) ([][]string, error) {
+	src := ex.Expect
+	if ex.ExpectFile != "" {
+		path := ex.ExpectFile
+		if !filepath.IsAbs(path) && q.Source != "" {
+			path = filepath.Join(filepath.Dir(q.Source), path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src = string(b)
+	}
+	lines := strings.Split(strings.TrimSpace(src), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	r := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	r.FieldsPerRecord = -1
+	return r.ReadAll()
+}
+
+// Run executes the example and compares its rows against the expected
+// CSV. Columns the CSV leaves out aren't checked.
+func Run(ctx context.Context, r *runner.Runner, q compiler.QueryDoc, ex compiler.Example) ([]Failure, error) {
+	want, err := Expected(q, ex)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(ex.Values))
+	for name, v := range ex.Values {
+		values[name] = v
+	}
+	result, err := r.Run(ctx, q, values)
+	if err != nil {
+		return nil, err
+	}
+	failures := make([]Failure, 0)
+	for _, msg := range compare(result, want) {
+		failures = append(failures, Failure{Query: q.Name, Example: ex.Name, Message: msg})
+	}
+	return failures, nil
+}
+
+func compare(result *runner.Result, want [][]string) []string {
+	problems := make([]string, 0)
+	if len(want) == 0 {
+		if result.RowCount > 0 {
+			problems = append(problems, fmt.Sprintf("expected no rows, got %d", result.RowCount))
+		}
+		return problems
+	}
+	header, rows := want[0], want[1:]
+	if len(result.Rows) > 0 {
+		for _, name := range header {
+			if _, ok := result.Rows[0][name]; !ok {
+				problems = append(problems, fmt.Sprintf("expected column %v isn't in the result", name))
+			}
+		}
+		if len(problems) > 0 {
+			return problems
+		}
+	}
+	if len(rows) != result.RowCount {
+		problems = append(problems, fmt.Sprintf("expected %d rows, got %d", len(rows), result.RowCount))
+	}
+	for i := 0; i < len(rows) && i < len(result.Rows); i++ {
+		for j, name := range header {
+			expected := ""
+			if j < len(rows[i]) {
+				expected = rows[i][j]
+			}
+			got := Format(result.Rows[i][name])
+			if got != expected {
+				problems = append(problems, fmt.Sprintf("row %d column %v: expected %q, got %q", i+1, name, expected, got))
+			}
+		}
+	}
+	return problems
+}
+
+// Format writes a result value the way it would appear in CSV. NULL is
+// empty.
+func Format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
+
+// Execer is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Seed loads fixtures into db. A .sql file is executed as a script; a
+// .csv file is inserted into the table named after it, using its header
+// as the column list. Empty CSV fields are inserted as NULL.
+func Seed(ctx context.Context, db Execer, paths ...string) error {
+	for _, path := range paths {
+		var err error
+		switch filepath.Ext(path) {
+		case ".sql":
+			err = seedScript(ctx, db, path)
+		case ".csv":
+			err = seedCSV(ctx, db, path)
+		default:
+			err = fmt.Errorf("don't know how to seed from %v", path)
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func seedScript(ctx context.Context, db Execer, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, string(b))
+	return err
+}
+
+func seedCSV(ctx context.Context, db Execer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	table := strings.TrimSuffix(filepath.Base(path), ".csv")
+	header := records[0]
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(header)), ", ")
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(header, ", "), placeholders)
+	for _, record := range records[1:] {
+		args := make([]interface{}, len(record))
+		for i, field := range record {
+			if field != "" {
+				args[i] = field
+			}
+		}
+		if _, err := db.ExecContext(ctx, statement, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
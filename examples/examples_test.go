@@ -0,0 +1,95 @@
+package examples
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/runner"
+	_ "modernc.org/sqlite"
+)
+
+func TestExpected(t *testing.T) {
+	ex := compiler.Example{Expect: `id,status
+		1,open
+		2,"open, again"`}
+	got, err := Expected(compiler.QueryDoc{}, ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[2][1] != "open, again" {
+		t.Errorf("Wrong rows. Got %q", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	result := &runner.Result{RowCount: 2, Rows: []map[string]interface{}{
+		{"id": int64(1), "status": "open", "extra": nil},
+		{"id": int64(2), "status": "closed", "extra": 1.5},
+	}}
+	problems := compare(result, [][]string{{"id", "extra"}, {"1", ""}, {"2", "1.5"}})
+	if len(problems) != 0 {
+		t.Errorf("Expected a match. Got %v", problems)
+	}
+	problems = compare(result, [][]string{{"id", "status"}, {"1", "open"}, {"2", "open"}, {"3", "open"}})
+	want := []string{
+		"expected 3 rows, got 2",
+		`row 2 column status: expected "open", got "closed"`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Wrong problems. Got %v want %v", problems, want)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("Wrong problem at %v. Got %v want %v", i, problems[i], want[i])
+		}
+	}
+	problems = compare(result, [][]string{{"nope"}})
+	if len(problems) != 1 {
+		t.Errorf("Expected a missing column. Got %v", problems)
+	}
+}
+
+func TestRun(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own.
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.sql")
+	orders := filepath.Join(dir, "orders.csv")
+	os.WriteFile(schema, []byte("CREATE TABLE orders (id INTEGER, status TEXT);"), 0o644)
+	os.WriteFile(orders, []byte("id,status\n1,open\n2,closed\n3,open\n"), 0o644)
+	ctx := context.Background()
+	if err := Seed(ctx, db, schema, orders); err != nil {
+		t.Fatal(err)
+	}
+	q := compiler.QueryDoc{
+		Name:   "orders",
+		SQL:    "SELECT id, status FROM orders WHERE status = ${status} ORDER BY id",
+		Params: []compiler.Param{{ProperName: "status", Type: compiler.String, Required: true}},
+	}
+	r := runner.New(db, "sqlite")
+	ex := compiler.Example{Name: "open", Values: map[string]string{"status": "open"}, Expect: "id\n1\n3"}
+	failures, err := Run(ctx, r, q, ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("Expected the example to pass. Got %v", failures)
+	}
+	ex = compiler.Example{Name: "closed", Values: map[string]string{"status": "closed"}, Expect: "id,status\n2,open"}
+	failures, err = Run(ctx, r, q, ex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].Example != "closed" {
+		t.Errorf("Wrong failures. Got %v", failures)
+	}
+}
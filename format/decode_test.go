@@ -0,0 +1,454 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The decoders here read back what the encoders write, as generic values
+// shaped like encoding/json's, so tests can compare them with the JSON
+// encoding. Each only covers the subset of its format the encoder uses.
+
+// yamlDecoder reads the block style YAML written by YAML.
+type yamlDecoder struct {
+	lines []string
+	i     int
+}
+
+func decodeYAML(s string) (interface{}, error) {
+	d := &yamlDecoder{lines: strings.Split(strings.TrimSuffix(s, "\n"), "\n")}
+	v, err := d.object(0, "")
+	if err == nil && d.i < len(d.lines) {
+		err = fmt.Errorf("line %d: unexpected %q", d.i+1, d.lines[d.i])
+	}
+	return v, err
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isDash(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// object reads the members at indent, the first already read as first if
+// it isn't empty.
+func (d *yamlDecoder) object(indent int, first string) (map[string]interface{}, error) {
+	o := make(map[string]interface{})
+	member := func(text string) error {
+		k, rest, err := splitKey(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", d.i, err)
+		}
+		o[k], err = d.value(rest, indent)
+		return err
+	}
+	if first != "" {
+		if err := member(first); err != nil {
+			return nil, err
+		}
+	}
+	for d.i < len(d.lines) {
+		line := d.lines[d.i]
+		text := strings.TrimLeft(line, " ")
+		if indentOf(line) != indent || isDash(text) {
+			break
+		}
+		d.i++
+		if err := member(text); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// value reads what follows a key or dash, base being the indent the
+// encoder wrote it at.
+func (d *yamlDecoder) value(rest string, base int) (interface{}, error) {
+	rest = strings.TrimPrefix(rest, " ")
+	switch rest {
+	case "":
+		if d.i == len(d.lines) {
+			return nil, fmt.Errorf("line %d: missing value", d.i)
+		}
+		line := d.lines[d.i]
+		if indentOf(line) == base && isDash(strings.TrimLeft(line, " ")) {
+			return d.seq(base)
+		}
+		if indentOf(line) == base+2 {
+			return d.object(base+2, "")
+		}
+		return nil, fmt.Errorf("line %d: wrong indent", d.i+1)
+	case "{}":
+		return map[string]interface{}{}, nil
+	case "[]":
+		return []interface{}{}, nil
+	case "|-":
+		lines := make([]string, 0)
+		for d.i < len(d.lines) && (d.lines[d.i] == "" || indentOf(d.lines[d.i]) >= base+2) {
+			line := d.lines[d.i]
+			if line != "" {
+				line = line[base+2:]
+			}
+			lines = append(lines, line)
+			d.i++
+		}
+		return strings.Join(lines, "\n"), nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if strings.HasPrefix(rest, `"`) {
+		var s string
+		err := json.Unmarshal([]byte(rest), &s)
+		return s, err
+	}
+	return strconv.ParseFloat(rest, 64)
+}
+
+func (d *yamlDecoder) seq(indent int) ([]interface{}, error) {
+	a := make([]interface{}, 0)
+	for d.i < len(d.lines) {
+		line := d.lines[d.i]
+		text := strings.TrimLeft(line, " ")
+		if indentOf(line) != indent || !isDash(text) {
+			break
+		}
+		d.i++
+		rest := text[1:]
+		var v interface{}
+		var err error
+		if _, _, kerr := splitKey(strings.TrimPrefix(rest, " ")); kerr == nil {
+			v, err = d.object(indent+2, rest[1:])
+		} else {
+			v, err = d.value(rest, indent+2)
+		}
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+// splitKey splits "key: rest", the key bare or quoted.
+func splitKey(text string) (string, string, error) {
+	if strings.HasPrefix(text, `"`) {
+		s, n, err := quotedPrefix(text)
+		if err != nil {
+			return "", "", err
+		}
+		if !strings.HasPrefix(text[n:], ":") {
+			return "", "", fmt.Errorf("no key in %q", text)
+		}
+		return s, text[n+1:], nil
+	}
+	i := strings.IndexByte(text, ':')
+	if i <= 0 || !bare(text[:i]) || (i+1 < len(text) && text[i+1] != ' ') {
+		return "", "", fmt.Errorf("no key in %q", text)
+	}
+	return text[:i], text[i+1:], nil
+}
+
+// quotedPrefix decodes the JSON string text starts with, returning its
+// length in text.
+func quotedPrefix(text string) (string, int, error) {
+	for n := 1; n < len(text); n++ {
+		switch text[n] {
+		case '\\':
+			n++
+		case '"':
+			var s string
+			err := json.Unmarshal([]byte(text[:n+1]), &s)
+			return s, n + 1, err
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string in %q", text)
+}
+
+// decodeTOML reads the tables, arrays of tables and inline values written
+// by TOML.
+func decodeTOML(s string) (interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+	for n, line := range strings.Split(s, "\n") {
+		var err error
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "[["):
+			current, err = tomlTable(root, strings.TrimSuffix(line[2:], "]]"), true)
+		case strings.HasPrefix(line, "["):
+			current, err = tomlTable(root, strings.TrimSuffix(line[1:], "]"), false)
+		default:
+			p := &tomlParser{s: line}
+			var k string
+			if k, err = p.key(); err == nil {
+				if err = p.expect(" = "); err == nil {
+					current[k], err = p.value()
+				}
+			}
+			if err == nil && p.i != len(p.s) {
+				err = fmt.Errorf("trailing %q", p.s[p.i:])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+	}
+	return root, nil
+}
+
+// tomlTable finds or makes the table at a dotted header, appending a new
+// one if it's an array of tables. Arrays on the way lead to their last
+// table.
+func tomlTable(root map[string]interface{}, header string, array bool) (map[string]interface{}, error) {
+	p := &tomlParser{s: header}
+	keys := make([]string, 0)
+	for {
+		k, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+		if p.i == len(p.s) {
+			break
+		}
+		if err := p.expect("."); err != nil {
+			return nil, err
+		}
+	}
+	t := root
+	for _, k := range keys[:len(keys)-1] {
+		switch v := t[k].(type) {
+		case map[string]interface{}:
+			t = v
+		case []interface{}:
+			t = v[len(v)-1].(map[string]interface{})
+		default:
+			return nil, fmt.Errorf("no table %v", k)
+		}
+	}
+	last := keys[len(keys)-1]
+	table := make(map[string]interface{})
+	if array {
+		a, _ := t[last].([]interface{})
+		t[last] = append(a, table)
+	} else {
+		t[last] = table
+	}
+	return table, nil
+}
+
+type tomlParser struct {
+	s string
+	i int
+}
+
+func (p *tomlParser) expect(s string) error {
+	if !strings.HasPrefix(p.s[p.i:], s) {
+		return fmt.Errorf("expected %q at %q", s, p.s[p.i:])
+	}
+	p.i += len(s)
+	return nil
+}
+
+func (p *tomlParser) key() (string, error) {
+	if strings.HasPrefix(p.s[p.i:], `"`) {
+		return p.str()
+	}
+	start := p.i
+	for p.i < len(p.s) && bare(p.s[p.i:p.i+1]) {
+		p.i++
+	}
+	if p.i == start {
+		return "", fmt.Errorf("expected a key at %q", p.s[start:])
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *tomlParser) str() (string, error) {
+	s, n, err := quotedPrefix(p.s[p.i:])
+	p.i += n
+	return s, err
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	rest := p.s[p.i:]
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		return p.str()
+	case strings.HasPrefix(rest, "true"):
+		p.i += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false"):
+		p.i += 5
+		return false, nil
+	case strings.HasPrefix(rest, "["):
+		p.i++
+		a := make([]interface{}, 0)
+		for !strings.HasPrefix(p.s[p.i:], "]") {
+			if len(a) > 0 {
+				if err := p.expect(", "); err != nil {
+					return nil, err
+				}
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		p.i++
+		return a, nil
+	case strings.HasPrefix(rest, "{"):
+		p.i++
+		o := make(map[string]interface{})
+		for !strings.HasPrefix(p.s[p.i:], " }") {
+			if len(o) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.expect(" "); err != nil {
+				return nil, err
+			}
+			k, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(" = "); err != nil {
+				return nil, err
+			}
+			if o[k], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		p.i += 2
+		return o, nil
+	}
+	end := strings.IndexAny(rest, ", ]}")
+	if end < 0 {
+		end = len(rest)
+	}
+	p.i += end
+	return strconv.ParseFloat(rest[:end], 64)
+}
+
+// protoField is a field of a message in docstringparser.proto.
+type protoField struct {
+	name     string
+	typ      string
+	repeated bool
+}
+
+var (
+	messagePattern = regexp.MustCompile(`(?s)message (\w+) \{(.*?)\n\}`)
+	fieldPattern   = regexp.MustCompile(`(?m)^\s*(repeated |optional )?(map<string, string>|\w+) (\w+) = (\d+);`)
+)
+
+// readProto reads the messages of the shipped .proto, by name and field
+// number.
+func readProto() (map[string]map[uint64]protoField, error) {
+	b, err := os.ReadFile("docstringparser.proto")
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string]map[uint64]protoField)
+	for _, m := range messagePattern.FindAllStringSubmatch(string(b), -1) {
+		fields := make(map[uint64]protoField)
+		for _, f := range fieldPattern.FindAllStringSubmatch(m[2], -1) {
+			n, _ := strconv.ParseUint(f[4], 10, 64)
+			fields[n] = protoField{name: f[3], typ: f[2], repeated: f[1] == "repeated "}
+		}
+		messages[m[1]] = fields
+	}
+	return messages, nil
+}
+
+// decodeProto decodes b as the named message, keyed by field name.
+func decodeProto(messages map[string]map[uint64]protoField, name string, b []byte) (map[string]interface{}, error) {
+	fields, ok := messages[name]
+	if !ok {
+		return nil, fmt.Errorf("no message %v", name)
+	}
+	m := make(map[string]interface{})
+	for len(b) > 0 {
+		tag, n := uvarint(b)
+		b = b[n:]
+		f, ok := fields[tag>>3]
+		if !ok {
+			return nil, fmt.Errorf("%v has no field %d", name, tag>>3)
+		}
+		var v interface{}
+		switch tag & 7 {
+		case wireVarint:
+			x, n := uvarint(b)
+			b = b[n:]
+			if f.typ == "bool" {
+				v = x == 1
+			} else {
+				v = int64(x)
+			}
+		case wireBytes:
+			size, n := uvarint(b)
+			if n+int(size) > len(b) {
+				return nil, fmt.Errorf("%v.%v runs past the end", name, f.name)
+			}
+			data := b[n : n+int(size)]
+			b = b[n+int(size):]
+			switch f.typ {
+			case "string":
+				v = string(data)
+			case "map<string, string>":
+				entry, err := decodeProto(map[string]map[uint64]protoField{"entry": {
+					1: {name: "key", typ: "string"},
+					2: {name: "value", typ: "string"},
+				}}, "entry", data)
+				if err != nil {
+					return nil, err
+				}
+				values, _ := m[f.name].(map[string]interface{})
+				if values == nil {
+					values = make(map[string]interface{})
+					m[f.name] = values
+				}
+				values[entry["key"].(string)] = entry["value"]
+				continue
+			default:
+				sub, err := decodeProto(messages, f.typ, data)
+				if err != nil {
+					return nil, err
+				}
+				v = sub
+			}
+		default:
+			return nil, fmt.Errorf("%v.%v has wire type %d", name, f.name, tag&7)
+		}
+		if f.repeated {
+			a, _ := m[f.name].([]interface{})
+			m[f.name] = append(a, v)
+		} else {
+			m[f.name] = v
+		}
+	}
+	return m, nil
+}
+
+func uvarint(b []byte) (uint64, int) {
+	var x uint64
+	for i, c := range b {
+		x |= uint64(c&0x7f) << (7 * i)
+		if c < 0x80 {
+			return x, i + 1
+		}
+	}
+	return x, len(b)
+}
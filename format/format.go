@@ -0,0 +1,211 @@
+// Package format encodes compiled docs for the systems that ingest them.
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+// Encoder writes docs in one format.
+type Encoder interface {
+	Encode(w io.Writer, docs []compiler.QueryDoc) error
+	// ContentType is the media type of what Encode writes.
+	ContentType() string
+}
+
+var (
+	mu       sync.RWMutex
+	encoders = make(map[string]Encoder)
+)
+
+// Register makes an encoder available by name and, through Negotiate, by
+// its content type. It replaces any encoder already registered by name.
+func Register(name string, e Encoder) {
+	mu.Lock()
+	defer mu.Unlock()
+	encoders[name] = e
+}
+
+// Lookup finds an encoder by name.
+func Lookup(name string) (Encoder, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := encoders[name]
+	return e, ok
+}
+
+// Names lists the registered encoders.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Negotiate picks the encoder for an Accept header: the acceptable media
+// type with the highest quality, the first of any tied. A quality of 0
+// means not acceptable. */* and an empty header get JSON.
+func Negotiate(accept string) (Encoder, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return Lookup("json")
+	}
+	names := Names()
+	var best Encoder
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		media, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		if media == "*/*" || media == "application/*" {
+			if e, ok := Lookup("json"); ok {
+				best, bestQ = e, q
+			}
+			continue
+		}
+		for _, name := range names {
+			if e, ok := Lookup(name); ok && e.ContentType() == media {
+				best, bestQ = e, q
+				break
+			}
+		}
+	}
+	return best, best != nil
+}
+
+func init() {
+	Register("json", JSON{})
+	Register("yaml", YAML{})
+	Register("toml", TOML{})
+	Register("protobuf", Protobuf{})
+}
+
+// JSON writes the versioned catalog envelope.
+type JSON struct{}
+
+func (JSON) ContentType() string {
+	return "application/json"
+}
+
+func (JSON) Encode(w io.Writer, docs []compiler.QueryDoc) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(catalog.New(docs))
+}
+
+// member is a key and value of a JSON object, kept in order.
+type member struct {
+	key   string
+	value interface{}
+}
+
+// object is a JSON object with its keys in order.
+type object []member
+
+// tree converts docs to the catalog envelope as generic values, objects
+// keeping the order of the JSON encoding, for the text formats to write.
+func tree(docs []compiler.QueryDoc) (object, error) {
+	b := bytes.Buffer{}
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(catalog.New(docs)); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(&b)
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	return v.(object), nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		o := make(object, 0)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, member{key.(string), value})
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		a := make([]interface{}, 0)
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err := dec.Token()
+		return a, err
+	}
+	return t, nil
+}
+
+// quote writes s as a double quoted string with JSON's escapes, which
+// YAML and TOML both read.
+func quote(s string) string {
+	b := bytes.Buffer{}
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// TOML doesn't allow a raw DEL, which JSON leaves be.
+	return strings.ReplaceAll(strings.TrimSuffix(b.String(), "\n"), "\x7f", `\u007f`)
+}
+
+// bare reports whether a key needs no quotes.
+func bare(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func key(k string) string {
+	if bare(k) {
+		return k
+	}
+	return quote(k)
+}
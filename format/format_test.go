@@ -0,0 +1,283 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var docs = []compiler.QueryDoc{{
+	Name:   "active",
+	Params: []compiler.Param{{ProperName: "since", Type: compiler.Date}},
+	Output: compiler.Table{Columns: []compiler.Column{{ProperName: "id", Type: compiler.Integer}}},
+	SQL:    "SELECT id\nFROM users",
+	Tags:   []string{"users"},
+}}
+
+func encode(t *testing.T, name string, docs []compiler.QueryDoc) string {
+	enc, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("No %v encoder", name)
+	}
+	b := bytes.Buffer{}
+	if err := enc.Encode(&b, docs); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestYAML(t *testing.T) {
+	got := encode(t, "yaml", docs)
+	for _, want := range []string{
+		"version: 1\ndocs:\n- name: \"active\"\n",
+		"  params:\n  - properName: \"since\"\n    type: \"date\"\n",
+		"    columns:\n    - properName: \"id\"\n",
+		"  sql: |-\n    SELECT id\n    FROM users\n",
+		"  tags:\n  - \"users\"\n",
+		"  policy: {}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("YAML is missing %q. Got\n%v", want, got)
+		}
+	}
+}
+
+func TestTOML(t *testing.T) {
+	got := encode(t, "toml", docs)
+	for _, want := range []string{
+		"version = 1\n\n[[docs]]\nname = \"active\"\n",
+		"sql = \"SELECT id\\nFROM users\"\n",
+		"tags = [\"users\"]\n",
+		"\n[[docs.params]]\nproperName = \"since\"\n",
+		"\n[docs.output]\n",
+		"\n[[docs.output.columns]]\nproperName = \"id\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("TOML is missing %q. Got\n%v", want, got)
+		}
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	got := encode(t, "protobuf", []compiler.QueryDoc{{Name: "a", SQL: "S"}})
+	want := "\x08\x01" + // version
+		"\x12\x0b" + // docs
+		"\x0a\x01a" + // name
+		"\x3a\x00" + // output
+		"\x42\x01S" + // sql
+		"\x82\x01\x00" // policy
+	if got != want {
+		t.Errorf("Wrong protobuf. Got %q want %q", got, want)
+	}
+}
+
+var empty = ""
+
+// rich covers what the encoders escape, indent or leave out.
+var rich = []compiler.QueryDoc{{
+	Name:        "rich",
+	Source:      "queries/rich.sql",
+	Title:       "Rich \"quoted\" title",
+	Description: "A tab\there, a DEL\x7f, and ünïcode ☃.",
+	Params: []compiler.Param{{
+		ProperName: "status",
+		Type:       compiler.String,
+		Blurb:      "Status: open or closed",
+		Required:   true,
+		Default:    &empty,
+		Enum:       []string{"open", "closed"},
+	}},
+	Output: compiler.Table{Title: "Orders", Columns: []compiler.Column{
+		{ProperName: "id", Type: compiler.Integer, Use: "id"},
+		{ProperName: "email", Type: compiler.String, Sensitive: true},
+	}},
+	SQL:      "SELECT id,\n  email\n\n-- y: z\n- y: z\nFROM orders",
+	See:      []string{"other"},
+	Examples: []compiler.Example{{Name: "yes", Values: map[string]string{"yes": "no", "a b": "\"c\""}, Expect: "id\n1"}},
+	Owner:    "someone",
+	Tags:     []string{"true", "1", ""},
+	Deprecated: &compiler.Deprecation{
+		Reason: "use other",
+	},
+	Policy:      compiler.Policy{Timeout: 5000000000, Cache: 60000000000, ReadOnly: true, MaxRows: 10},
+	Requires:    []string{"admin"},
+	Incomplete:  true,
+	Diagnostics: compiler.Diagnostics{{Severity: compiler.SeverityError, Message: "bad: \"thing\""}},
+	Includes:    []string{"shared"},
+}, {
+	Name:   "minimal",
+	Params: []compiler.Param{},
+	Output: compiler.Table{Columns: []compiler.Column{}},
+}}
+
+// generic is what the JSON encoder writes for docs, decoded.
+func generic(t *testing.T, docs []compiler.QueryDoc) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(encode(t, "json", docs)), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// dropNulls removes null members, which TOML can't write.
+func dropNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if e == nil {
+				delete(v, k)
+			} else {
+				v[k] = dropNulls(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = dropNulls(e)
+		}
+	}
+	return v
+}
+
+// dropZero removes the zero values protobuf doesn't write, keeping an
+// optional default, and makes numbers float64 as JSON's are.
+func dropZero(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			e = dropZero(e)
+			if k != "default" && isZero(e) {
+				delete(v, k)
+			} else {
+				v[k] = e
+			}
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = dropZero(e)
+		}
+		return v
+	case int64:
+		return float64(v)
+	}
+	return v
+}
+
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// camel renames the decoded proto's fields as the JSON names them.
+func camel(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			switch k {
+			case "timeout_nanos":
+				k = "timeout"
+			case "cache_nanos":
+				k = "cache"
+			}
+			parts := strings.Split(k, "_")
+			for i := 1; i < len(parts); i++ {
+				parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+			}
+			if k != "values" {
+				e = camel(e)
+			}
+			m[strings.Join(parts, "")] = e
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = camel(e)
+		}
+	}
+	return v
+}
+
+func TestYAMLDecodes(t *testing.T) {
+	for _, docs := range [][]compiler.QueryDoc{docs, rich, nil} {
+		out := encode(t, "yaml", docs)
+		got, err := decodeYAML(out)
+		if err != nil {
+			t.Fatalf("Unexpected err %v decoding\n%v", err, out)
+		}
+		if want := generic(t, docs); !reflect.DeepEqual(got, want) {
+			t.Errorf("YAML doesn't decode to the docs. Got %v want %v from\n%v", got, want, out)
+		}
+	}
+}
+
+func TestTOMLDecodes(t *testing.T) {
+	for _, docs := range [][]compiler.QueryDoc{docs, rich, nil} {
+		out := encode(t, "toml", docs)
+		got, err := decodeTOML(out)
+		if err != nil {
+			t.Fatalf("Unexpected err %v decoding\n%v", err, out)
+		}
+		if want := dropNulls(generic(t, docs)); !reflect.DeepEqual(got, want) {
+			t.Errorf("TOML doesn't decode to the docs. Got %v want %v from\n%v", got, want, out)
+		}
+	}
+}
+
+func TestProtobufDecodes(t *testing.T) {
+	messages, err := readProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, docs := range [][]compiler.QueryDoc{docs, rich} {
+		decoded, err := decodeProto(messages, "Catalog", []byte(encode(t, "protobuf", docs)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := dropZero(camel(decoded))
+		if want := dropZero(generic(t, docs)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Protobuf doesn't decode to the docs. Got %v want %v", got, want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                                  "application/json",
+		"*/*":                               "application/json",
+		"application/yaml":                  "application/yaml",
+		"text/html, application/toml;q=0.9": "application/toml",
+		"application/x-protobuf":            "application/x-protobuf",
+		"application/yaml;q=0, */*":         "application/json",
+		"application/toml;q=0.5, application/yaml":       "application/yaml",
+		"application/yaml;q=0.2, application/toml;q=0.8": "application/toml",
+	}
+	for accept, want := range cases {
+		enc, ok := Negotiate(accept)
+		if !ok || enc.ContentType() != want {
+			t.Errorf("Wrong encoder for %q. Got %v want %v", accept, enc, want)
+		}
+	}
+	for _, accept := range []string{"text/html", "application/yaml;q=0", "*/*;q=0"} {
+		if enc, ok := Negotiate(accept); ok {
+			t.Errorf("Expected no encoder for %q. Got %v", accept, enc)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package format
+
+import (
+	"io"
+	"sort"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// Protobuf writes a Catalog message as defined in docstringparser.proto.
+type Protobuf struct{}
+
+func (Protobuf) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (Protobuf) Encode(w io.Writer, docs []compiler.QueryDoc) error {
+	m := message{}
+	m.varint(1, catalog.FormatVersion)
+	for _, q := range docs {
+		m.message(2, encodeDoc(q))
+	}
+	_, err := w.Write(m)
+	return err
+}
+
+// message is a protobuf message being encoded. Fields at their zero value
+// are left out, as proto3 does.
+type message []byte
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (m *message) tag(field, wire int) {
+	m.uvarint(uint64(field)<<3 | uint64(wire))
+}
+
+func (m *message) uvarint(v uint64) {
+	for v >= 0x80 {
+		*m = append(*m, byte(v)|0x80)
+		v >>= 7
+	}
+	*m = append(*m, byte(v))
+}
+
+func (m *message) varint(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	m.tag(field, wireVarint)
+	m.uvarint(uint64(v))
+}
+
+func (m *message) bool(field int, v bool) {
+	if v {
+		m.varint(field, 1)
+	}
+}
+
+func (m *message) bytes(field int, b []byte) {
+	m.tag(field, wireBytes)
+	m.uvarint(uint64(len(b)))
+	*m = append(*m, b...)
+}
+
+func (m *message) string(field int, s string) {
+	if s != "" {
+		m.bytes(field, []byte(s))
+	}
+}
+
+func (m *message) strings(field int, ss []string) {
+	for _, s := range ss {
+		m.bytes(field, []byte(s))
+	}
+}
+
+// message writes a sub-message, even an empty one, so the field is set.
+func (m *message) message(field int, sub message) {
+	m.bytes(field, sub)
+}
+
+func encodeDoc(q compiler.QueryDoc) message {
+	m := message{}
+	m.string(1, q.Name)
+	m.string(2, q.Source)
+	m.string(3, q.Title)
+	m.string(4, q.Description)
+	m.string(5, q.DescriptionHTML)
+	for _, p := range q.Params {
+		m.message(6, encodeParam(p))
+	}
+	m.message(7, encodeTable(q.Output))
+	m.string(8, q.SQL)
+	m.strings(9, q.See)
+	for _, ex := range q.Examples {
+		m.message(10, encodeExample(ex))
+	}
+	m.string(11, q.Owner)
+	m.string(12, q.Team)
+	m.strings(13, q.Tags)
+	m.string(14, q.Since)
+	if q.Deprecated != nil {
+		d := message{}
+		d.string(1, q.Deprecated.Reason)
+		d.string(2, q.Deprecated.ReplacedBy)
+		m.message(15, d)
+	}
+	p := message{}
+	p.varint(1, int64(q.Policy.Timeout))
+	p.varint(2, int64(q.Policy.Cache))
+	p.bool(3, q.Policy.ReadOnly)
+	p.varint(4, int64(q.Policy.MaxRows))
+	m.message(16, p)
+	m.strings(17, q.Requires)
+	m.bool(18, q.Incomplete)
+	for _, d := range q.Diagnostics {
+		dm := message{}
+		dm.message(1, encodePosition(d.Pos))
+		dm.string(2, string(d.Severity))
+		dm.string(3, d.Message)
+		m.message(19, dm)
+	}
+	m.string(20, q.Define)
+	m.strings(21, q.Includes)
+	return m
+}
+
+func encodeParam(p compiler.Param) message {
+	m := message{}
+	m.string(1, p.ProperName)
+	m.string(2, string(p.Type))
+	m.string(3, p.Blurb)
+	m.string(4, p.Description)
+	m.string(5, p.DescriptionHTML)
+	m.bool(6, p.Required)
+	if p.Default != nil {
+		// The default is optional, so even an empty one is written.
+		m.bytes(7, []byte(*p.Default))
+	}
+	m.strings(8, p.Enum)
+	m.string(9, p.Use)
+	return m
+}
+
+func encodeTable(t compiler.Table) message {
+	m := message{}
+	m.string(1, t.Title)
+	m.string(2, t.Description)
+	m.string(3, t.DescriptionHTML)
+	for _, c := range t.Columns {
+		cm := message{}
+		cm.string(1, c.ProperName)
+		cm.string(2, string(c.Type))
+		cm.string(3, c.Blurb)
+		cm.string(4, c.Description)
+		cm.string(5, c.DescriptionHTML)
+		cm.bool(6, c.Sensitive)
+		cm.string(7, c.Use)
+		m.message(4, cm)
+	}
+	return m
+}
+
+func encodeExample(ex compiler.Example) message {
+	m := message{}
+	m.string(1, ex.Name)
+	names := make([]string, 0, len(ex.Values))
+	for name := range ex.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Map entries are messages with the key as field 1 and the value
+		// as field 2.
+		entry := message{}
+		entry.string(1, name)
+		entry.string(2, ex.Values[name])
+		m.message(2, entry)
+	}
+	m.string(3, ex.Expect)
+	m.string(4, ex.ExpectFile)
+	return m
+}
+
+func encodePosition(pos tokenizer.Position) message {
+	m := message{}
+	m.varint(1, int64(pos.Offset))
+	m.varint(2, int64(pos.Line))
+	m.varint(3, int64(pos.Column))
+	return m
+}
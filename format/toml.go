@@ -0,0 +1,122 @@
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+// TOML writes the catalog envelope as TOML, docs as an array of tables.
+// TOML has no null, so null values are left out.
+type TOML struct{}
+
+func (TOML) ContentType() string {
+	return "application/toml"
+}
+
+func (TOML) Encode(w io.Writer, docs []compiler.QueryDoc) error {
+	t, err := tree(docs)
+	if err != nil {
+		return err
+	}
+	b := bufio.NewWriter(w)
+	writeTOMLTable(b, t, nil)
+	return b.Flush()
+}
+
+// writeTOMLTable writes o's values and then its tables, which TOML needs
+// to come after them. path is o's key from the root.
+func writeTOMLTable(w *bufio.Writer, o object, path []string) {
+	for _, m := range o {
+		if m.value == nil || tableLike(m.value) {
+			continue
+		}
+		w.WriteString(key(m.key) + " = ")
+		writeTOMLValue(w, m.value)
+		w.WriteString("\n")
+	}
+	for _, m := range o {
+		if !tableLike(m.value) {
+			continue
+		}
+		sub := append(path[:len(path):len(path)], key(m.key))
+		switch v := m.value.(type) {
+		case object:
+			w.WriteString("\n[" + strings.Join(sub, ".") + "]\n")
+			writeTOMLTable(w, v, sub)
+		case []interface{}:
+			for _, item := range v {
+				w.WriteString("\n[[" + strings.Join(sub, ".") + "]]\n")
+				writeTOMLTable(w, item.(object), sub)
+			}
+		}
+	}
+}
+
+// tableLike reports whether v is written as a table or array of tables
+// rather than inline.
+func tableLike(v interface{}) bool {
+	switch v := v.(type) {
+	case object:
+		return true
+	case []interface{}:
+		if len(v) == 0 {
+			return false
+		}
+		for _, item := range v {
+			if _, ok := item.(object); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// writeTOMLValue writes a scalar or an inline array or table.
+func writeTOMLValue(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case object:
+		w.WriteString("{")
+		first := true
+		for _, m := range v {
+			if m.value == nil {
+				continue
+			}
+			if !first {
+				w.WriteString(",")
+			}
+			first = false
+			w.WriteString(" " + key(m.key) + " = ")
+			writeTOMLValue(w, m.value)
+		}
+		w.WriteString(" }")
+	case []interface{}:
+		w.WriteString("[")
+		first := true
+		for _, item := range v {
+			if item == nil {
+				continue
+			}
+			if !first {
+				w.WriteString(", ")
+			}
+			first = false
+			writeTOMLValue(w, item)
+		}
+		w.WriteString("]")
+	case string:
+		w.WriteString(quote(v))
+	case json.Number:
+		w.WriteString(v.String())
+	case bool:
+		if v {
+			w.WriteString("true")
+		} else {
+			w.WriteString("false")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+// YAML writes the catalog envelope as YAML, multi-line strings such as SQL
+// as literal blocks.
+type YAML struct{}
+
+func (YAML) ContentType() string {
+	return "application/yaml"
+}
+
+func (YAML) Encode(w io.Writer, docs []compiler.QueryDoc) error {
+	t, err := tree(docs)
+	if err != nil {
+		return err
+	}
+	b := bufio.NewWriter(w)
+	writeYAMLObject(b, t, "")
+	return b.Flush()
+}
+
+// reserved are keys YAML 1.1 readers would take for something other than a
+// string.
+var reserved = map[string]bool{
+	"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+	"true": true, "false": true, "null": true, "~": true,
+}
+
+func yamlKey(k string) string {
+	if reserved[strings.ToLower(k)] {
+		return quote(k)
+	}
+	return key(k)
+}
+
+// writeYAMLObject writes o's members one per line at indent.
+func writeYAMLObject(w *bufio.Writer, o object, indent string) {
+	for i, m := range o {
+		if i > 0 {
+			w.WriteString(indent)
+		}
+		w.WriteString(yamlKey(m.key) + ":")
+		writeYAMLValue(w, m.value, indent)
+	}
+}
+
+// writeYAMLValue writes v after a key or dash, on the same line if it's a
+// scalar or empty and on the lines below otherwise.
+func writeYAMLValue(w *bufio.Writer, v interface{}, indent string) {
+	switch v := v.(type) {
+	case object:
+		if len(v) == 0 {
+			w.WriteString(" {}\n")
+			return
+		}
+		w.WriteString("\n" + indent + "  ")
+		writeYAMLObject(w, v, indent+"  ")
+	case []interface{}:
+		if len(v) == 0 {
+			w.WriteString(" []\n")
+			return
+		}
+		w.WriteString("\n")
+		for _, item := range v {
+			w.WriteString(indent + "-")
+			if o, ok := item.(object); ok && len(o) > 0 {
+				w.WriteString(" ")
+				writeYAMLObject(w, o, indent+"  ")
+				continue
+			}
+			writeYAMLValue(w, item, indent+"  ")
+		}
+	case string:
+		if literal(v) {
+			w.WriteString(" |-\n")
+			for _, line := range strings.Split(v, "\n") {
+				if line != "" {
+					w.WriteString(indent + "  " + line)
+				}
+				w.WriteString("\n")
+			}
+			return
+		}
+		w.WriteString(" " + quote(v) + "\n")
+	case json.Number:
+		w.WriteString(" " + v.String() + "\n")
+	case bool:
+		if v {
+			w.WriteString(" true\n")
+		} else {
+			w.WriteString(" false\n")
+		}
+	default:
+		w.WriteString(" null\n")
+	}
+}
+
+// literal reports whether s can be written as a |- block and read back
+// unchanged.
+func literal(s string) bool {
+	if !strings.Contains(s, "\n") || strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\n") || strings.HasSuffix(s, "\n") {
+		return false
+	}
+	for _, r := range s {
+		if r != '\n' && (r < ' ' && r != '\t' || r == 0x7f || r == '\ufeff') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package jsonschema
+
+import (
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of a JSON Schema document needed to describe
+// query parameters.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+// ForQuery builds an object schema with a property for each of the query's
+// params.
+func ForQuery(q compiler.QueryDoc) *Schema {
+	closed := false
+	s := &Schema{
+		Schema:               Draft,
+		Title:                q.Title,
+		Description:          q.Description,
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		Required:             make([]string, 0),
+		AdditionalProperties: &closed,
+	}
+	for _, p := range q.Params {
+		s.Properties[p.ProperName] = ForParam(p)
+		if p.Required {
+			s.Required = append(s.Required, p.ProperName)
+		}
+	}
+	return s
+}
+
+// ForQueries builds a schema for each query, in order.
+func ForQueries(docs []compiler.QueryDoc) []*Schema {
+	schemas := make([]*Schema, 0, len(docs))
+	for _, q := range docs {
+		schemas = append(schemas, ForQuery(q))
+	}
+	return schemas
+}
+
+// ForParam builds the schema for a single param.
+func ForParam(p compiler.Param) *Schema {
+	s := ForType(p.Type)
+	s.Title = p.Blurb
+	s.Description = p.Description
+	if p.Default != nil {
+		s.Default = value(p.Type, *p.Default)
+	}
+	if len(p.Enum) > 0 {
+		s.Enum = make([]interface{}, 0, len(p.Enum))
+		for _, e := range p.Enum {
+			s.Enum = append(s.Enum, value(p.Type, e))
+		}
+	}
+	return s
+}
+
+// Nullable builds a schema accepting either null or a value matching s.
+func Nullable(s *Schema) *Schema {
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
+
+// ForType builds the schema for a value of the given type.
+func ForType(t compiler.Type) *Schema {
+	switch t {
+	case compiler.Integer:
+		return &Schema{Type: "integer"}
+	case compiler.Number:
+		return &Schema{Type: "number"}
+	case compiler.Boolean:
+		return &Schema{Type: "boolean"}
+	case compiler.Date:
+		return &Schema{Type: "string", Format: "date"}
+	case compiler.DateTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	return &Schema{Type: "string"}
+}
+
+// value converts a literal the compiler has already validated.
+func value(t compiler.Type, s string) interface{} {
+	v, err := t.Value(s)
+	if err != nil {
+		return s
+	}
+	return v
+}
@@ -0,0 +1,35 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+func TestForQuery(t *testing.T) {
+	limit := "10"
+	q := compiler.QueryDoc{
+		Title: "Orders",
+		Params: []compiler.Param{
+			{ProperName: "status", Type: compiler.String, Required: true, Enum: []string{"open", "closed"}},
+			{ProperName: "limit", Type: compiler.Integer, Default: &limit},
+			{ProperName: "since", Type: compiler.Date},
+		},
+	}
+	s := ForQuery(q)
+	if s.Schema != Draft || s.Type != "object" {
+		t.Errorf("Wrong top level schema. Got %v %v", s.Schema, s.Type)
+	}
+	if len(s.Required) != 1 || s.Required[0] != "status" {
+		t.Errorf("Wrong required list. Got %v want %v", s.Required, []string{"status"})
+	}
+	if len(s.Properties["status"].Enum) != 2 {
+		t.Errorf("Wrong enum. Got %v", s.Properties["status"].Enum)
+	}
+	if s.Properties["limit"].Default != int64(10) {
+		t.Errorf("Wrong default. Got %#v want %#v", s.Properties["limit"].Default, int64(10))
+	}
+	if s.Properties["since"].Format != "date" {
+		t.Errorf("Wrong format. Got %v want %v", s.Properties["since"].Format, "date")
+	}
+}
@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// Warning is a problem that doesn't stop a catalog from compiling.
+type Warning struct {
+	Source  string             `json:"source"`
+	Query   string             `json:"query"`
+	Pos     tokenizer.Position `json:"pos"`
+	Message string             `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s:%v: %s: %s", w.Source, w.Pos, w.Query, w.Message)
+}
+
+// Catalog checks references between docs: @see pointing at a query that
+// doesn't exist or is deprecated, and @deprecated naming a replacement
+// that doesn't exist.
+func Catalog(c *catalog.Catalog) []Warning {
+	warnings := make([]Warning, 0)
+	for _, q := range c.Docs {
+		warn := func(format string, args ...interface{}) {
+			warnings = append(warnings, Warning{Source: q.Source, Query: q.Name, Pos: q.Pos, Message: fmt.Sprintf(format, args...)})
+		}
+		for _, name := range q.See {
+			ref, ok := c.Lookup(name)
+			if !ok {
+				warn("@see %v doesn't exist", name)
+				continue
+			}
+			if ref.Deprecated == nil {
+				continue
+			}
+			if ref.Deprecated.ReplacedBy != "" {
+				warn("@see %v is deprecated, use %v: %v", name, ref.Deprecated.ReplacedBy, ref.Deprecated.Reason)
+			} else {
+				warn("@see %v is deprecated: %v", name, ref.Deprecated.Reason)
+			}
+		}
+		if q.Deprecated != nil && q.Deprecated.ReplacedBy != "" {
+			if _, ok := c.Lookup(q.Deprecated.ReplacedBy); !ok {
+				warn("replacement %v doesn't exist", q.Deprecated.ReplacedBy)
+			}
+		}
+	}
+	return warnings
+}
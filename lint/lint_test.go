@@ -0,0 +1,30 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+func TestCatalog(t *testing.T) {
+	c := catalog.New([]compiler.QueryDoc{
+		{Name: "old", Deprecated: &compiler.Deprecation{Reason: "slow", ReplacedBy: "new"}},
+		{Name: "older", Deprecated: &compiler.Deprecation{Reason: "gone", ReplacedBy: "missing"}},
+		{Name: "new", See: []string{"old", "unknown"}},
+	})
+	want := []string{
+		"replacement missing doesn't exist",
+		"@see old is deprecated, use new: slow",
+		"@see unknown doesn't exist",
+	}
+	got := Catalog(c)
+	if len(got) != len(want) {
+		t.Fatalf("Wrong warnings. Got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Message != want[i] {
+			t.Errorf("Wrong warning at %v. Got %v want %v", i, got[i].Message, want[i])
+		}
+	}
+}
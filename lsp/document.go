@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+type document struct {
+	uri   string
+	lines []string
+	// compiled are the docs as compiled, definitions included, and
+	// problems what went wrong compiling them.
+	compiled []compiler.QueryDoc
+	problems compiler.Diagnostics
+	// docs are the queries once resolved, and diags every problem.
+	docs  []compiler.QueryDoc
+	diags compiler.Diagnostics
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, lines: strings.Split(text, "\n")}
+	tok := tokenizer.NewTokenizer(strings.NewReader(text))
+	tokErr := tok.Tokenize()
+	if tokErr != nil {
+		d.problems = append(d.problems, compiler.AsDiagnostics(tokErr)...)
+	}
+	docs, err := compiler.Compile(tok.Tokens())
+	if err != nil {
+		d.problems = append(d.problems, compiler.AsDiagnostics(err)...)
+	}
+	d.compiled = docs
+	return d
+}
+
+// definitions are the shared definitions the document declares, their
+// positions cleared, for other documents to resolve against.
+func (d *document) definitions() []compiler.QueryDoc {
+	defs := make([]compiler.QueryDoc, 0)
+	for _, doc := range d.compiled {
+		if doc.Define != "" {
+			doc.Pos, doc.End = tokenizer.Position{}, tokenizer.Position{}
+			defs = append(defs, doc)
+		}
+	}
+	return defs
+}
+
+// resolve resolves the document's docs against its own definitions and
+// those of other documents. Problems with the others' definitions are
+// theirs to report, and are left out by their cleared positions. A name
+// defined both here and elsewhere is reported here.
+func (d *document) resolve(others []compiler.QueryDoc) {
+	docs, err := compiler.Resolve(append(others, d.compiled...))
+	d.docs = docs
+	d.diags = append(compiler.Diagnostics(nil), d.problems...)
+	if err == nil {
+		return
+	}
+	for _, diag := range compiler.AsDiagnostics(err) {
+		if diag.Pos != (tokenizer.Position{}) {
+			d.diags = append(d.diags, diag)
+		}
+	}
+}
+
+func (d *document) line(n int) []rune {
+	if n < 0 || n >= len(d.lines) {
+		return nil
+	}
+	return []rune(strings.TrimSuffix(d.lines[n], "\r"))
+}
+
+// toLSP converts a tokenizer position, which counts runes from 1, to an LSP
+// position, which counts UTF-16 code units from 0.
+func (d *document) toLSP(p tokenizer.Position) Position {
+	line := d.line(p.Line - 1)
+	col := p.Column - 1
+	if col > len(line) {
+		col = len(line)
+	}
+	if col < 0 {
+		col = 0
+	}
+	return Position{Line: p.Line - 1, Character: len(utf16.Encode(line[:col]))}
+}
+
+// fromLSP converts an LSP position to a tokenizer position.
+func (d *document) fromLSP(p Position) tokenizer.Position {
+	line := d.line(p.Line)
+	units := 0
+	col := 0
+	for col < len(line) && units < p.Character {
+		units += len(utf16.Encode(line[col : col+1]))
+		col++
+	}
+	return tokenizer.Position{Line: p.Line + 1, Column: col + 1}
+}
+
+func (d *document) rangeAt(p tokenizer.Position, length int) Range {
+	start := d.toLSP(p)
+	end := d.toLSP(tokenizer.Position{Line: p.Line, Column: p.Column + length})
+	return Range{Start: start, End: end}
+}
+
+// enclosing finds the doc whose comment or statement contains p.
+func (d *document) enclosing(p tokenizer.Position) (compiler.QueryDoc, bool) {
+	for _, doc := range d.docs {
+		if !before(p, doc.Pos) && !before(doc.End, p) {
+			return doc, true
+		}
+	}
+	return compiler.QueryDoc{}, false
+}
+
+func before(a, b tokenizer.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordAt returns the word under p, the text on the line before it, and
+// the position where it starts.
+func (d *document) wordAt(p tokenizer.Position) (word, prefix string, start tokenizer.Position) {
+	line := d.line(p.Line - 1)
+	col := p.Column - 1
+	if col > len(line) {
+		col = len(line)
+	}
+	from, to := col, col
+	for from > 0 && isWordRune(line[from-1]) {
+		from--
+	}
+	for to < len(line) && isWordRune(line[to]) {
+		to++
+	}
+	return string(line[from:to]), string(line[:from]), tokenizer.Position{Line: p.Line, Column: from + 1}
+}
@@ -0,0 +1,409 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+// annotations offered for completion after an @.
+var annotations = map[string]string{
+	"title":       `@title "Title"`,
+	"description": `@description "Markdown description"`,
+	"name":        "@name query_name",
+	"param":       `@param name [type] "Blurb" "Description" or @param use:name`,
+	"required":    "@required, marks the preceding @param as required",
+	"default":     `@default "value", for the preceding @param`,
+	"enum":        `@enum "a" "b", allowed values of the preceding @param`,
+	"table":       "@table { ... }",
+	"column":      `@column name [type] "Blurb" "Description" or @column use:name`,
+	"see":         "@see query_name",
+	"example":     `@example "name" { @value param "v" @expect "csv" }`,
+	"value":       `@value param "value", inside an @example`,
+	"expect":      `@expect "csv" or @expect file "rows.csv", inside an @example`,
+	"owner":       `@owner "someone@example.com"`,
+	"team":        `@team "team"`,
+	"tags":        `@tags "a" "b"`,
+	"since":       `@since "version"`,
+	"deprecated":  `@deprecated "reason" [replacement_query]`,
+	"timeout":     "@timeout 30s",
+	"cache":       "@cache 5m",
+	"readonly":    "@readonly, rejects statements that can write",
+	"maxrows":     "@maxrows 1000",
+	"requires":    "@requires role, role",
+	"sensitive":   "@sensitive, marks the preceding @column as sensitive",
+	"define":      "@define name, makes the doc shared definitions rather than a query",
+	"include":     "@include name, name, the params and columns of shared definitions",
+}
+
+// Server is a language server for doc comments in .sql files, speaking
+// over a pair of streams, normally stdin and stdout.
+type Server struct {
+	conn *conn
+	// open holds documents the client has open, workspace those read from
+	// disk at startup.
+	open      map[string]*document
+	workspace map[string]*document
+	shutdown  bool
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn:      newConn(r, w),
+		open:      make(map[string]*document),
+		workspace: make(map[string]*document),
+	}
+}
+
+// Run serves requests until the client exits or the input closes.
+func (s *Server) Run() error {
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if rpcErr, ok := err.(*rpcError); ok {
+			s.conn.reply(nil, nil, rpcErr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// Notifications get no reply, even on failure.
+			continue
+		}
+		err = s.conn.reply(msg.ID, result, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) (interface{}, error) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.loadWorkspace(params.RootURI)
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:   syncFull,
+				CompletionProvider: &CompletionOptions{TriggerCharacters: []string{"@", "{"}},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "DocStringParser"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// With full sync the last change holds the whole text.
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(params.TextDocument.URI, text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.open, params.TextDocument.URI)
+		return nil, s.resolve()
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	}
+	if msg.ID == nil {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+func unmarshal(raw json.RawMessage, v interface{}) error {
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// loadWorkspace reads every .sql file under root so @see can resolve to
+// files that aren't open.
+func (s *Server) loadWorkspace(rootURI string) {
+	root := uriToPath(rootURI)
+	if root == "" {
+		return
+	}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".sql") {
+			return nil
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		uri := pathToURI(path)
+		s.workspace[uri] = newDocument(uri, string(text))
+		return nil
+	})
+	s.resolve()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// update replaces an open document. Since other documents may use its
+// definitions, every open document is resolved and published again.
+func (s *Server) update(uri, text string) error {
+	s.open[uri] = newDocument(uri, text)
+	return s.resolve()
+}
+
+// resolve resolves every document against the definitions in all of
+// them, publishing the diagnostics of those open.
+func (s *Server) resolve() error {
+	docs := s.documents()
+	for _, d := range docs {
+		others := make([]compiler.QueryDoc, 0)
+		for _, other := range docs {
+			if other.uri != d.uri {
+				others = append(others, other.definitions()...)
+			}
+		}
+		d.resolve(others)
+	}
+	for _, d := range sorted(s.open) {
+		if err := s.publish(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) publish(d *document) error {
+	diags := make([]Diagnostic, 0, len(d.diags))
+	for _, diag := range d.diags {
+		severity := severityError
+		if diag.Severity == compiler.SeverityWarning {
+			severity = severityWarning
+		}
+		diags = append(diags, Diagnostic{
+			Range:    d.rangeAt(diag.Pos, 1),
+			Severity: severity,
+			Source:   "docstring",
+			Message:  diag.Message,
+		})
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: d.uri, Diagnostics: diags})
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	d, ok := s.open[params.TextDocument.URI]
+	if !ok {
+		return items
+	}
+	pos := d.fromLSP(params.Position)
+	// Only complete the part of the word before the cursor.
+	_, prefix, start := d.wordAt(pos)
+	typed := string(d.line(pos.Line - 1)[start.Column-1 : pos.Column-1])
+	switch {
+	case strings.HasSuffix(prefix, "@"):
+		names := make([]string, 0, len(annotations))
+		for name := range annotations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if strings.HasPrefix(name, typed) {
+				items = append(items, CompletionItem{Label: name, Kind: kindKeyword, Detail: annotations[name]})
+			}
+		}
+	case strings.HasSuffix(prefix, "${"):
+		doc, ok := d.enclosing(pos)
+		if !ok {
+			return items
+		}
+		for _, p := range doc.Params {
+			if strings.HasPrefix(p.ProperName, typed) {
+				items = append(items, CompletionItem{
+					Label:         p.ProperName,
+					Kind:          kindVariable,
+					Detail:        p.Blurb,
+					Documentation: p.Description,
+				})
+			}
+		}
+	case strings.HasSuffix(strings.TrimRight(prefix, " \t"), "@see"):
+		for _, doc := range s.allDocs() {
+			if strings.HasPrefix(doc.Name, typed) {
+				items = append(items, CompletionItem{Label: doc.Name, Kind: kindVariable, Detail: doc.Title})
+			}
+		}
+	}
+	return items
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	d, ok := s.open[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	pos := d.fromLSP(params.Position)
+	word, prefix, start := d.wordAt(pos)
+	if word == "" {
+		return nil
+	}
+	r := d.rangeAt(start, len([]rune(word)))
+	trimmed := strings.TrimRight(prefix, " \t")
+	if strings.HasSuffix(trimmed, "@see") {
+		doc, _, ok := s.lookup(word)
+		if !ok {
+			return nil
+		}
+		return &Hover{Contents: markdown(fmt.Sprintf("**%s** (`%s`)\n\n%s", doc.Title, doc.Name, doc.Description)), Range: &r}
+	}
+	if !strings.HasSuffix(prefix, "${") && !strings.HasSuffix(trimmed, "@param") {
+		return nil
+	}
+	doc, ok := d.enclosing(pos)
+	if !ok {
+		return nil
+	}
+	for _, p := range doc.Params {
+		if p.ProperName == word {
+			card := fmt.Sprintf("**%s** `%s %s`", p.Blurb, p.ProperName, p.Type)
+			if p.Required {
+				card += " (required)"
+			}
+			if p.Description != "" {
+				card += "\n\n" + p.Description
+			}
+			return &Hover{Contents: markdown(card), Range: &r}
+		}
+	}
+	return nil
+}
+
+func markdown(s string) MarkupContent {
+	return MarkupContent{Kind: "markdown", Value: s}
+}
+
+func (s *Server) definition(params TextDocumentPositionParams) []Location {
+	locations := make([]Location, 0)
+	d, ok := s.open[params.TextDocument.URI]
+	if !ok {
+		return locations
+	}
+	word, prefix, _ := d.wordAt(d.fromLSP(params.Position))
+	if word == "" || !strings.HasSuffix(strings.TrimRight(prefix, " \t"), "@see") {
+		return locations
+	}
+	doc, in, ok := s.lookup(word)
+	if !ok {
+		return locations
+	}
+	return append(locations, Location{URI: in.uri, Range: in.rangeAt(doc.Pos, 3)})
+}
+
+// documents lists open documents, then workspace documents that aren't
+// open, each in URI order.
+func (s *Server) documents() []*document {
+	docs := sorted(s.open)
+	for _, d := range sorted(s.workspace) {
+		if _, open := s.open[d.uri]; !open {
+			docs = append(docs, d)
+		}
+	}
+	return docs
+}
+
+func sorted(set map[string]*document) []*document {
+	docs := make([]*document, 0, len(set))
+	for _, d := range set {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].uri < docs[j].uri
+	})
+	return docs
+}
+
+func (s *Server) allDocs() []compiler.QueryDoc {
+	all := make([]compiler.QueryDoc, 0)
+	for _, d := range s.documents() {
+		all = append(all, d.docs...)
+	}
+	return all
+}
+
+func (s *Server) lookup(name string) (compiler.QueryDoc, *document, bool) {
+	for _, d := range s.documents() {
+		for _, doc := range d.docs {
+			if doc.Name == name {
+				return doc, d, true
+			}
+		}
+	}
+	return compiler.QueryDoc{}, nil, false
+}
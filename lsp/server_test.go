@@ -0,0 +1,190 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const source = `/**
+@name active_users
+@title "Active users"
+@param since date "Since" "Only users seen after this"
+@see purge_users
+*/
+SELECT name FROM users WHERE seen > ${since};
+
+/**
+@name purge_users
+@title "Purge users"
+*/
+DELETE FROM users;
+`
+
+func frame(id int, method string, params interface{}) string {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+	body, _ := json.Marshal(msg)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///q.sql"},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func run(t *testing.T, text string, requests ...string) map[string]json.RawMessage {
+	in := strings.Builder{}
+	in.WriteString(frame(1, "initialize", InitializeParams{}))
+	in.WriteString(frame(0, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: "file:///q.sql", Text: text},
+	}))
+	for _, r := range requests {
+		in.WriteString(r)
+	}
+	out := bytes.Buffer{}
+	err := NewServer(strings.NewReader(in.String()), &out).Run()
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	results := make(map[string]json.RawMessage)
+	c := newConn(&out, nil)
+	for {
+		msg, err := c.read()
+		if err != nil {
+			break
+		}
+		if msg.ID != nil {
+			results[string(*msg.ID)] = json.RawMessage(mustMarshal(msg.Result))
+		} else {
+			results[msg.Method] = msg.Params
+		}
+		if msg.Method == "textDocument/publishDiagnostics" {
+			// Keep the last for each document too.
+			var params PublishDiagnosticsParams
+			json.Unmarshal(msg.Params, &params)
+			results[msg.Method+" "+params.URI] = msg.Params
+		}
+	}
+	return results
+}
+
+func mustMarshal(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func TestDiagnostics(t *testing.T) {
+	results := run(t, "/**\n@param x wat \"X\" \"Y\"\n*/\nSELECT 1;")
+	var params PublishDiagnosticsParams
+	json.Unmarshal(results["textDocument/publishDiagnostics"], &params)
+	if len(params.Diagnostics) != 1 {
+		t.Fatalf("Wrong number of diagnostics. Got %v want %v", len(params.Diagnostics), 1)
+	}
+	want := Position{Line: 1, Character: 9}
+	if params.Diagnostics[0].Range.Start != want {
+		t.Errorf("Wrong diagnostic position. Got %v want %v", params.Diagnostics[0].Range.Start, want)
+	}
+}
+
+func TestResolveDiagnostics(t *testing.T) {
+	results := run(t, "/**\n@define shared\n@param id int \"ID\" \"\"\n*/\n\n/**\n@name q\n@include missing\n*/\nSELECT 1;")
+	var params PublishDiagnosticsParams
+	json.Unmarshal(results["textDocument/publishDiagnostics"], &params)
+	if len(params.Diagnostics) != 1 || params.Diagnostics[0].Message != "query q includes unknown definition missing" {
+		t.Fatalf("Wrong diagnostics. Got %+v", params.Diagnostics)
+	}
+	if want := (Position{Line: 5, Character: 0}); params.Diagnostics[0].Range.Start != want {
+		t.Errorf("Wrong diagnostic position. Got %v want %v", params.Diagnostics[0].Range.Start, want)
+	}
+}
+
+func TestResolveAcrossDocuments(t *testing.T) {
+	defs := "/**\n@define shared\n@param since date \"Since\" \"\"\n*/\n"
+	results := run(t, "/**\n@name q\n@include shared\n@param use:shared.since\n*/\nSELECT ${since};",
+		frame(0, "textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: "file:///defs.sql", Text: defs},
+		}),
+		frame(2, "textDocument/completion", at(5, 9)),
+	)
+	var params PublishDiagnosticsParams
+	json.Unmarshal(results["textDocument/publishDiagnostics file:///q.sql"], &params)
+	if len(params.Diagnostics) != 0 {
+		t.Errorf("Definitions in another document weren't found. Got %+v", params.Diagnostics)
+	}
+	var items []CompletionItem
+	json.Unmarshal(results["2"], &items)
+	if len(items) != 1 || items[0].Label != "since" {
+		t.Errorf("Wrong param completions. Got %v want %v", items, "since")
+	}
+}
+
+func TestCompletionIncluded(t *testing.T) {
+	text := "/**\n@define shared\n@param since date \"Since\" \"\"\n*/\n\n/**\n@name q\n@include shared\n*/\nSELECT ${s"
+	results := run(t, text, frame(2, "textDocument/completion", at(9, 10)))
+	var items []CompletionItem
+	json.Unmarshal(results["2"], &items)
+	if len(items) != 1 || items[0].Label != "since" {
+		t.Errorf("Wrong param completions. Got %v want %v", items, "since")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	results := run(t, source,
+		frame(2, "textDocument/completion", at(6, 40)),
+		frame(3, "textDocument/completion", at(2, 4)),
+	)
+	var items []CompletionItem
+	json.Unmarshal(results["2"], &items)
+	if len(items) != 1 || items[0].Label != "since" {
+		t.Errorf("Wrong param completions. Got %v want %v", items, "since")
+	}
+	json.Unmarshal(results["3"], &items)
+	if len(items) != 1 || items[0].Label != "title" {
+		t.Errorf("Wrong annotation completions. Got %v want %v", items, "title")
+	}
+}
+
+func TestHover(t *testing.T) {
+	results := run(t, source, frame(2, "textDocument/hover", at(6, 39)))
+	var hover Hover
+	json.Unmarshal(results["2"], &hover)
+	if !strings.Contains(hover.Contents.Value, "Only users seen after this") {
+		t.Errorf("Wrong hover. Got %q", hover.Contents.Value)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	results := run(t, source, frame(2, "textDocument/definition", at(4, 7)))
+	var locations []Location
+	json.Unmarshal(results["2"], &locations)
+	if len(locations) != 1 || locations[0].Range.Start.Line != 8 {
+		t.Errorf("Wrong definition. Got %v want line %v", locations, 8)
+	}
+}
+
+func TestParseErrorReply(t *testing.T) {
+	out := bytes.Buffer{}
+	err := NewServer(strings.NewReader("Content-Length: 5\r\n\r\n{nope"), &out).Run()
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if !strings.Contains(out.String(), `"id":null`) || !strings.Contains(out.String(), `"code":-32700`) {
+		t.Errorf("Wrong reply to a parse error. Got %s", out.String())
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	for _, length := range []string{"-1", "999999999999"} {
+		err := NewServer(strings.NewReader("Content-Length: "+length+"\r\n\r\n{}"), &bytes.Buffer{}).Run()
+		if err == nil || !strings.Contains(err.Error(), "bad Content-Length") {
+			t.Errorf("Wrong error for Content-Length %v. Got %v", length, err)
+		}
+	}
+}
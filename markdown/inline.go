@@ -0,0 +1,265 @@
+package markdown
+
+import (
+	"strings"
+)
+
+const escapable = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+type inlineParser struct {
+	src  string
+	pos  int
+	text strings.Builder
+	out  []Inline
+}
+
+func parseInlines(src string) []Inline {
+	p := inlineParser{src: src, out: make([]Inline, 0)}
+	return p.parse()
+}
+
+func (p *inlineParser) flush() {
+	if p.text.Len() > 0 {
+		p.out = append(p.out, Text{Literal: p.text.String()})
+		p.text.Reset()
+	}
+}
+
+func (p *inlineParser) emit(i Inline) {
+	p.flush()
+	p.out = append(p.out, i)
+}
+
+func (p *inlineParser) parse() []Inline {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case '\\':
+			if p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n' {
+				p.emit(HardBreak{})
+				p.pos += 2
+			} else if p.pos+1 < len(p.src) && strings.IndexByte(escapable, p.src[p.pos+1]) >= 0 {
+				p.text.WriteByte(p.src[p.pos+1])
+				p.pos += 2
+			} else {
+				p.text.WriteByte(c)
+				p.pos++
+			}
+		case '\n':
+			if strings.HasSuffix(p.text.String(), "  ") {
+				trimmed := strings.TrimRight(p.text.String(), " ")
+				p.text.Reset()
+				p.text.WriteString(trimmed)
+				p.emit(HardBreak{})
+			} else {
+				p.trimTrailingSpace()
+				p.emit(SoftBreak{})
+			}
+			p.pos++
+		case '`':
+			if !p.codeSpan() {
+				p.skipRun('`')
+			}
+		case '*', '_':
+			if !p.emphasis(c) {
+				p.skipRun(c)
+			}
+		case '[':
+			if !p.link() {
+				p.text.WriteByte(c)
+				p.pos++
+			}
+		case '<':
+			if !p.autolink() {
+				p.text.WriteByte(c)
+				p.pos++
+			}
+		default:
+			p.text.WriteByte(c)
+			p.pos++
+		}
+	}
+	p.flush()
+	return p.out
+}
+
+func (p *inlineParser) trimTrailingSpace() {
+	trimmed := strings.TrimRight(p.text.String(), " ")
+	p.text.Reset()
+	p.text.WriteString(trimmed)
+}
+
+func (p *inlineParser) run(c byte) int {
+	n := 0
+	for p.pos+n < len(p.src) && p.src[p.pos+n] == c {
+		n++
+	}
+	return n
+}
+
+func (p *inlineParser) skipRun(c byte) {
+	n := p.run(c)
+	p.text.WriteString(p.src[p.pos : p.pos+n])
+	p.pos += n
+}
+
+func (p *inlineParser) codeSpan() bool {
+	n := p.run('`')
+	fence := p.src[p.pos : p.pos+n]
+	for i := p.pos + n; i < len(p.src); {
+		end := strings.Index(p.src[i:], fence)
+		if end < 0 {
+			return false
+		}
+		end += i
+		if end+n < len(p.src) && p.src[end+n] == '`' {
+			// Longer run of backticks, keep looking.
+			i = end + n
+			for i < len(p.src) && p.src[i] == '`' {
+				i++
+			}
+			continue
+		}
+		literal := strings.ReplaceAll(p.src[p.pos+n:end], "\n", " ")
+		if len(literal) > 2 && literal[0] == ' ' && literal[len(literal)-1] == ' ' && strings.TrimSpace(literal) != "" {
+			literal = literal[1 : len(literal)-1]
+		}
+		p.emit(Code{Literal: literal})
+		p.pos = end + n
+		return true
+	}
+	return false
+}
+
+func (p *inlineParser) emphasis(c byte) bool {
+	n := p.run(c)
+	if n > 2 {
+		n = 2
+	}
+	start := p.pos + n
+	if start >= len(p.src) || p.src[start] == ' ' || p.src[start] == '\n' {
+		return false
+	}
+	if c == '_' && p.pos > 0 && isWordByte(p.src[p.pos-1]) {
+		return false
+	}
+	delim := p.src[p.pos : p.pos+n]
+	for i := start + 1; i <= len(p.src)-n; i++ {
+		if p.src[i] == '\\' {
+			i++
+			continue
+		}
+		if p.src[i] == '`' {
+			// Delimiters inside code spans don't count.
+			if end := strings.IndexByte(p.src[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+			continue
+		}
+		if p.src[i:i+n] != delim || p.src[i-1] == ' ' || p.src[i-1] == '\n' {
+			continue
+		}
+		after := i + n
+		if after < len(p.src) && p.src[after] == c {
+			if n == 1 {
+				// Skip over a nested strong run.
+				i = after
+				continue
+			}
+		}
+		if c == '_' && after < len(p.src) && isWordByte(p.src[after]) {
+			continue
+		}
+		children := parseInlines(p.src[start:i])
+		if n == 2 {
+			p.emit(Strong{Children: children})
+		} else {
+			p.emit(Emphasis{Children: children})
+		}
+		p.pos = after
+		return true
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func (p *inlineParser) link() bool {
+	depth := 0
+	closeText := -1
+	for i := p.pos; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		if depth == 0 {
+			closeText = i
+			break
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(p.src) || p.src[closeText+1] != '(' {
+		return false
+	}
+	closeDest := strings.IndexByte(p.src[closeText+2:], ')')
+	if closeDest < 0 {
+		return false
+	}
+	closeDest += closeText + 2
+	dest, title := splitDestination(strings.TrimSpace(p.src[closeText+2 : closeDest]))
+	p.emit(Link{
+		Destination: dest,
+		Title:       title,
+		Children:    parseInlines(p.src[p.pos+1 : closeText]),
+	})
+	p.pos = closeDest + 1
+	return true
+}
+
+func splitDestination(s string) (dest, title string) {
+	if strings.HasPrefix(s, "<") {
+		if end := strings.IndexByte(s, '>'); end > 0 {
+			dest, s = s[1:end], strings.TrimSpace(s[end+1:])
+		}
+	} else if space := strings.IndexAny(s, " \t\n"); space >= 0 {
+		dest, s = s[:space], strings.TrimSpace(s[space:])
+	} else {
+		return s, ""
+	}
+	if len(s) >= 2 {
+		switch s[0] {
+		case '"', '\'':
+			if s[len(s)-1] == s[0] {
+				title = s[1 : len(s)-1]
+			}
+		}
+	}
+	return dest, title
+}
+
+func (p *inlineParser) autolink() bool {
+	end := strings.IndexByte(p.src[p.pos:], '>')
+	if end < 0 {
+		return false
+	}
+	target := p.src[p.pos+1 : p.pos+end]
+	if strings.ContainsAny(target, " \t\n<") {
+		return false
+	}
+	dest := target
+	switch {
+	case strings.Contains(target, "://"):
+	case strings.Contains(target, "@") && !strings.Contains(target, ":"):
+		dest = "mailto:" + target
+	default:
+		return false
+	}
+	p.emit(Link{Destination: dest, Children: []Inline{Text{Literal: target}}})
+	p.pos += end + 1
+	return true
+}
@@ -0,0 +1,326 @@
+package markdown
+
+import (
+	"strings"
+)
+
+// Document is the root of a parsed description.
+type Document struct {
+	Blocks []Block
+}
+
+type (
+	Block interface {
+		block()
+	}
+
+	Paragraph struct {
+		Inlines []Inline
+	}
+
+	Heading struct {
+		Level   int
+		Inlines []Inline
+	}
+
+	CodeBlock struct {
+		Info    string
+		Literal string
+	}
+
+	BlockQuote struct {
+		Blocks []Block
+	}
+
+	List struct {
+		Ordered bool
+		Start   int
+		Items   []ListItem
+	}
+
+	ListItem struct {
+		Blocks []Block
+	}
+
+	ThematicBreak struct{}
+)
+
+func (Paragraph) block()     {}
+func (Heading) block()       {}
+func (CodeBlock) block()     {}
+func (BlockQuote) block()    {}
+func (List) block()          {}
+func (ThematicBreak) block() {}
+
+type (
+	Inline interface {
+		inline()
+	}
+
+	Text struct {
+		Literal string
+	}
+
+	Code struct {
+		Literal string
+	}
+
+	Emphasis struct {
+		Children []Inline
+	}
+
+	Strong struct {
+		Children []Inline
+	}
+
+	Link struct {
+		Destination string
+		Title       string
+		Children    []Inline
+	}
+
+	SoftBreak struct{}
+
+	HardBreak struct{}
+)
+
+func (Text) inline()      {}
+func (Code) inline()      {}
+func (Emphasis) inline()  {}
+func (Strong) inline()    {}
+func (Link) inline()      {}
+func (SoftBreak) inline() {}
+func (HardBreak) inline() {}
+
+// Parse parses src as a subset of CommonMark: paragraphs, ATX headings,
+// fenced code, block quotes, bullet and ordered lists, thematic breaks,
+// emphasis, inline code and links. The common indentation of src is removed
+// first since descriptions are usually indented inside a doc comment.
+func Parse(src string) *Document {
+	lines := dedent(strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"))
+	return &Document{Blocks: parseBlocks(lines)}
+}
+
+func dedent(lines []string) []string {
+	// The first line starts right after the opening quote, so only the
+	// following lines carry the comment's indentation.
+	indent := -1
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, len(lines))
+	out[0] = strings.TrimLeft(lines[0], " \t")
+	for i, line := range lines[1:] {
+		if indent > 0 && len(line) >= indent {
+			out[i+1] = line[indent:]
+		} else {
+			out[i+1] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return out
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func parseBlocks(lines []string) []Block {
+	blocks := make([]Block, 0)
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case isBlank(line):
+			i++
+		case isThematicBreak(trimmed):
+			blocks = append(blocks, ThematicBreak{})
+			i++
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+			blocks = append(blocks, Heading{Level: level, Inlines: parseInlines(text)})
+			i++
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			var code CodeBlock
+			code, i = parseFence(lines, i)
+			blocks = append(blocks, code)
+		case strings.HasPrefix(trimmed, ">"):
+			var quote BlockQuote
+			quote, i = parseQuote(lines, i)
+			blocks = append(blocks, quote)
+		case listMarker(trimmed) != nil:
+			var list List
+			list, i = parseList(lines, i)
+			blocks = append(blocks, list)
+		default:
+			var para Paragraph
+			para, i = parseParagraph(lines, i)
+			blocks = append(blocks, para)
+		}
+	}
+	return blocks
+}
+
+func isThematicBreak(line string) bool {
+	line = strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(line) < 3 {
+		return false
+	}
+	switch line[0] {
+	case '-', '*', '_':
+		return strings.Count(line, line[:1]) == len(line)
+	}
+	return false
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0
+	}
+	return level
+}
+
+func parseFence(lines []string, i int) (CodeBlock, int) {
+	open := strings.TrimLeft(lines[i], " ")
+	fence := open[:3]
+	code := CodeBlock{Info: strings.TrimSpace(strings.TrimLeft(open, fence[:1]))}
+	body := make([]string, 0)
+	for i++; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+			i++
+			break
+		}
+		body = append(body, lines[i])
+	}
+	if len(body) > 0 {
+		code.Literal = strings.Join(body, "\n") + "\n"
+	}
+	return code, i
+}
+
+func parseQuote(lines []string, i int) (BlockQuote, int) {
+	inner := make([]string, 0)
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if !strings.HasPrefix(trimmed, ">") {
+			break
+		}
+		trimmed = strings.TrimPrefix(trimmed, ">")
+		inner = append(inner, strings.TrimPrefix(trimmed, " "))
+	}
+	return BlockQuote{Blocks: parseBlocks(inner)}, i
+}
+
+type marker struct {
+	ordered bool
+	start   int
+	char    byte
+	width   int
+}
+
+func listMarker(line string) *marker {
+	if len(line) < 2 {
+		return nil
+	}
+	switch line[0] {
+	case '-', '*', '+':
+		if line[1] == ' ' || line[1] == '\t' {
+			return &marker{char: line[0], width: 2}
+		}
+		return nil
+	}
+	n := 0
+	for n < len(line) && n < 9 && line[n] >= '0' && line[n] <= '9' {
+		n++
+	}
+	if n == 0 || n+1 >= len(line) {
+		return nil
+	}
+	if (line[n] == '.' || line[n] == ')') && (line[n+1] == ' ' || line[n+1] == '\t') {
+		start := 0
+		for _, d := range line[:n] {
+			start = start*10 + int(d-'0')
+		}
+		return &marker{ordered: true, start: start, char: line[n], width: n + 2}
+	}
+	return nil
+}
+
+func parseList(lines []string, i int) (List, int) {
+	first := listMarker(strings.TrimLeft(lines[i], " "))
+	list := List{Ordered: first.ordered, Start: first.start, Items: make([]ListItem, 0)}
+	for i < len(lines) {
+		indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+		m := listMarker(lines[i][indent:])
+		if m == nil || m.ordered != first.ordered || m.char != first.char {
+			break
+		}
+		offset := indent + m.width
+		item := []string{lines[i][offset:]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= offset {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= offset {
+				item = append(item, line[offset:])
+				continue
+			}
+			trimmed := strings.TrimLeft(line, " ")
+			if listMarker(trimmed) != nil || startsBlock(trimmed) {
+				break
+			}
+			// Lazy continuation of the item's paragraph.
+			item = append(item, trimmed)
+		}
+		list.Items = append(list.Items, ListItem{Blocks: parseBlocks(item)})
+		if i < len(lines) && isBlank(lines[i]) {
+			i++
+		}
+	}
+	return list, i
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func startsBlock(trimmed string) bool {
+	return isThematicBreak(trimmed) ||
+		headingLevel(trimmed) > 0 ||
+		strings.HasPrefix(trimmed, "```") ||
+		strings.HasPrefix(trimmed, "~~~") ||
+		strings.HasPrefix(trimmed, ">")
+}
+
+func parseParagraph(lines []string, i int) (Paragraph, int) {
+	text := make([]string, 0)
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if isBlank(trimmed) {
+			break
+		}
+		if len(text) > 0 && (startsBlock(trimmed) || listMarker(trimmed) != nil) {
+			break
+		}
+		text = append(text, trimmed)
+	}
+	return Paragraph{Inlines: parseInlines(strings.Join(text, "\n"))}, i
+}
@@ -0,0 +1,66 @@
+package markdown
+
+import (
+	"testing"
+)
+
+var htmlTests = []struct {
+	src  string
+	want string
+}{
+	{"plain text", "<p>plain text</p>\n"},
+	{"some `inline code` here", "<p>some <code>inline code</code> here</p>\n"},
+	{"*em* and **strong**", "<p><em>em</em> and <strong>strong</strong></p>\n"},
+	{"snake_case_name", "<p>snake_case_name</p>\n"},
+	{"[a link](https://example.com \"Title\")", "<p><a href=\"https://example.com\" title=\"Title\">a link</a></p>\n"},
+	{"<https://example.com>", "<p><a href=\"https://example.com\">https://example.com</a></p>\n"},
+	{"[bad](javascript:alert(1))", "<p><a href=\"#\">bad</a>)</p>\n"},
+	{"one\ntwo", "<p>one\ntwo</p>\n"},
+	{"a <b> & c", "<p>a &lt;b&gt; &amp; c</p>\n"},
+	{"# Heading", "<h1>Heading</h1>\n"},
+	{"- one\n- two", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n"},
+	{"3. three\n4. four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n"},
+	{"intro\n- item", "<p>intro</p>\n<ul>\n<li>item</li>\n</ul>\n"},
+	{"```sql\nSELECT 1;\n```", "<pre><code class=\"language-sql\">SELECT 1;\n</code></pre>\n"},
+	{"> quoted", "<blockquote>\n<p>quoted</p>\n</blockquote>\n"},
+	{"first\n\n---", "<p>first</p>\n<hr />\n"},
+}
+
+func TestHTML(t *testing.T) {
+	for _, test := range htmlTests {
+		got := Parse(test.src).HTML()
+		if got != test.want {
+			t.Errorf("Wrong HTML for %q. Got %q want %q", test.src, got, test.want)
+		}
+	}
+}
+
+const indented = `The first line
+    - sits after the quote
+    - while these are indented`
+
+func TestDedent(t *testing.T) {
+	want := "<p>The first line</p>\n<ul>\n<li>sits after the quote</li>\n<li>while these are indented</li>\n</ul>\n"
+	got := Parse(indented).HTML()
+	if got != want {
+		t.Errorf("Wrong HTML for indented text. Got %q want %q", got, want)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	for _, test := range htmlTests {
+		md := Parse(test.src).Markdown()
+		got := Parse(md).HTML()
+		if got != test.want {
+			t.Errorf("Round trip of %q through %q changed HTML. Got %q want %q", test.src, md, got, test.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	got := Parse("Uses **bold** and `code`\n\n- a\n- b").PlainText()
+	want := "Uses bold and code\n\na\nb"
+	if got != want {
+		t.Errorf("Wrong plain text. Got %q want %q", got, want)
+	}
+}
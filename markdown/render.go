@@ -0,0 +1,283 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTML renders the document as an HTML fragment.
+func (d *Document) HTML() string {
+	if d == nil {
+		return ""
+	}
+	b := strings.Builder{}
+	writeHTMLBlocks(&b, d.Blocks, false)
+	return b.String()
+}
+
+// Markdown renders the document back out as normalized CommonMark.
+func (d *Document) Markdown() string {
+	if d == nil {
+		return ""
+	}
+	b := strings.Builder{}
+	writeMarkdownBlocks(&b, d.Blocks, "")
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// PlainText renders the document with all formatting stripped.
+func (d *Document) PlainText() string {
+	if d == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(d.Blocks))
+	for _, block := range d.Blocks {
+		parts = append(parts, plainBlock(block))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func writeHTMLBlocks(b *strings.Builder, blocks []Block, tight bool) {
+	for _, block := range blocks {
+		switch blk := block.(type) {
+		case Paragraph:
+			if tight {
+				writeHTMLInlines(b, blk.Inlines)
+			} else {
+				b.WriteString("<p>")
+				writeHTMLInlines(b, blk.Inlines)
+				b.WriteString("</p>\n")
+			}
+		case Heading:
+			fmt.Fprintf(b, "<h%d>", blk.Level)
+			writeHTMLInlines(b, blk.Inlines)
+			fmt.Fprintf(b, "</h%d>\n", blk.Level)
+		case CodeBlock:
+			b.WriteString("<pre><code")
+			if blk.Info != "" {
+				fmt.Fprintf(b, ` class="language-%s"`, html.EscapeString(strings.Fields(blk.Info)[0]))
+			}
+			b.WriteString(">")
+			b.WriteString(html.EscapeString(blk.Literal))
+			b.WriteString("</code></pre>\n")
+		case BlockQuote:
+			b.WriteString("<blockquote>\n")
+			writeHTMLBlocks(b, blk.Blocks, false)
+			b.WriteString("</blockquote>\n")
+		case List:
+			tag := "ul"
+			if blk.Ordered {
+				tag = "ol"
+			}
+			if blk.Ordered && blk.Start != 1 {
+				fmt.Fprintf(b, "<%s start=\"%d\">\n", tag, blk.Start)
+			} else {
+				fmt.Fprintf(b, "<%s>\n", tag)
+			}
+			for _, item := range blk.Items {
+				b.WriteString("<li>")
+				writeHTMLBlocks(b, item.Blocks, len(item.Blocks) == 1)
+				b.WriteString("</li>\n")
+			}
+			fmt.Fprintf(b, "</%s>\n", tag)
+		case ThematicBreak:
+			b.WriteString("<hr />\n")
+		}
+	}
+}
+
+func writeHTMLInlines(b *strings.Builder, inlines []Inline) {
+	for _, inline := range inlines {
+		switch in := inline.(type) {
+		case Text:
+			b.WriteString(html.EscapeString(in.Literal))
+		case Code:
+			b.WriteString("<code>")
+			b.WriteString(html.EscapeString(in.Literal))
+			b.WriteString("</code>")
+		case Emphasis:
+			b.WriteString("<em>")
+			writeHTMLInlines(b, in.Children)
+			b.WriteString("</em>")
+		case Strong:
+			b.WriteString("<strong>")
+			writeHTMLInlines(b, in.Children)
+			b.WriteString("</strong>")
+		case Link:
+			fmt.Fprintf(b, `<a href="%s"`, html.EscapeString(safeURL(in.Destination)))
+			if in.Title != "" {
+				fmt.Fprintf(b, ` title="%s"`, html.EscapeString(in.Title))
+			}
+			b.WriteString(">")
+			writeHTMLInlines(b, in.Children)
+			b.WriteString("</a>")
+		case SoftBreak:
+			b.WriteString("\n")
+		case HardBreak:
+			b.WriteString("<br />\n")
+		}
+	}
+}
+
+// safeURL drops script URLs, since descriptions end up in served pages.
+func safeURL(u string) string {
+	scheme := strings.ToLower(strings.TrimSpace(u))
+	if strings.HasPrefix(scheme, "javascript:") || strings.HasPrefix(scheme, "vbscript:") || strings.HasPrefix(scheme, "data:") {
+		return "#"
+	}
+	return u
+}
+
+func writeMarkdownBlocks(b *strings.Builder, blocks []Block, prefix string) {
+	for i, block := range blocks {
+		if i > 0 {
+			b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+		}
+		switch blk := block.(type) {
+		case Paragraph:
+			b.WriteString(prefix)
+			b.WriteString(strings.ReplaceAll(markdownInlines(blk.Inlines), "\n", "\n"+prefix))
+			b.WriteString("\n")
+		case Heading:
+			fmt.Fprintf(b, "%s%s %s\n", prefix, strings.Repeat("#", blk.Level), markdownInlines(blk.Inlines))
+		case CodeBlock:
+			fmt.Fprintf(b, "%s```%s\n", prefix, blk.Info)
+			if blk.Literal != "" {
+				for _, line := range strings.Split(strings.TrimSuffix(blk.Literal, "\n"), "\n") {
+					b.WriteString(prefix + line + "\n")
+				}
+			}
+			b.WriteString(prefix + "```\n")
+		case BlockQuote:
+			writeMarkdownBlocks(b, blk.Blocks, prefix+"> ")
+		case List:
+			for n, item := range blk.Items {
+				bullet := "- "
+				if blk.Ordered {
+					bullet = fmt.Sprintf("%d. ", blk.Start+n)
+				}
+				inner := strings.Builder{}
+				writeMarkdownBlocks(&inner, item.Blocks, "")
+				indent := strings.Repeat(" ", len(bullet))
+				for l, line := range strings.Split(strings.TrimRight(inner.String(), "\n"), "\n") {
+					switch {
+					case l == 0:
+						b.WriteString(prefix + bullet + line + "\n")
+					case line == "":
+						b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+					default:
+						b.WriteString(prefix + indent + line + "\n")
+					}
+				}
+			}
+		case ThematicBreak:
+			b.WriteString(prefix + "---\n")
+		}
+	}
+}
+
+func markdownInlines(inlines []Inline) string {
+	b := strings.Builder{}
+	for _, inline := range inlines {
+		switch in := inline.(type) {
+		case Text:
+			b.WriteString(Escape(in.Literal))
+		case Code:
+			b.WriteString(CodeSpan(in.Literal))
+		case Emphasis:
+			b.WriteString("*" + markdownInlines(in.Children) + "*")
+		case Strong:
+			b.WriteString("**" + markdownInlines(in.Children) + "**")
+		case Link:
+			b.WriteString("[" + markdownInlines(in.Children) + "](" + in.Destination)
+			if in.Title != "" {
+				b.WriteString(` "` + strings.ReplaceAll(in.Title, `"`, `\"`) + `"`)
+			}
+			b.WriteString(")")
+		case SoftBreak:
+			b.WriteString("\n")
+		case HardBreak:
+			b.WriteString("\\\n")
+		}
+	}
+	return b.String()
+}
+
+// CodeSpan writes s as a Markdown code span, fenced with more backticks
+// than it holds.
+func CodeSpan(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
+
+// Escape backslash escapes the characters of s that Markdown would read
+// as formatting.
+func Escape(s string) string {
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\', '`', '*', '_', '[', ']', '<':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func plainBlock(block Block) string {
+	switch blk := block.(type) {
+	case Paragraph:
+		return plainInlines(blk.Inlines)
+	case Heading:
+		return plainInlines(blk.Inlines)
+	case CodeBlock:
+		return strings.TrimSuffix(blk.Literal, "\n")
+	case BlockQuote:
+		parts := make([]string, 0, len(blk.Blocks))
+		for _, b := range blk.Blocks {
+			parts = append(parts, plainBlock(b))
+		}
+		return strings.Join(parts, "\n")
+	case List:
+		parts := make([]string, 0, len(blk.Items))
+		for _, item := range blk.Items {
+			inner := make([]string, 0, len(item.Blocks))
+			for _, b := range item.Blocks {
+				inner = append(inner, plainBlock(b))
+			}
+			parts = append(parts, strings.Join(inner, " "))
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+func plainInlines(inlines []Inline) string {
+	b := strings.Builder{}
+	for _, inline := range inlines {
+		switch in := inline.(type) {
+		case Text:
+			b.WriteString(in.Literal)
+		case Code:
+			b.WriteString(in.Literal)
+		case Emphasis:
+			b.WriteString(plainInlines(in.Children))
+		case Strong:
+			b.WriteString(plainInlines(in.Children))
+		case Link:
+			b.WriteString(plainInlines(in.Children))
+		case SoftBreak:
+			b.WriteString(" ")
+		case HardBreak:
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
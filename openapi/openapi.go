@@ -0,0 +1,250 @@
+package openapi
+
+import (
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/jsonschema"
+)
+
+const Version = "3.1.0"
+
+type (
+	Document struct {
+		OpenAPI           string                `json:"openapi"`
+		Info              Info                  `json:"info"`
+		JSONSchemaDialect string                `json:"jsonSchemaDialect"`
+		Paths             map[string]*PathItem  `json:"paths"`
+		Components        Components            `json:"components"`
+		Security          []map[string][]string `json:"security"`
+	}
+
+	Components struct {
+		SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+	}
+
+	SecurityScheme struct {
+		Type        string `json:"type"`
+		Scheme      string `json:"scheme"`
+		Description string `json:"description,omitempty"`
+	}
+
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	PathItem struct {
+		Get  *Operation `json:"get,omitempty"`
+		Post *Operation `json:"post,omitempty"`
+	}
+
+	Operation struct {
+		OperationID string               `json:"operationId"`
+		Summary     string               `json:"summary,omitempty"`
+		Description string               `json:"description,omitempty"`
+		Parameters  []Parameter          `json:"parameters,omitempty"`
+		RequestBody *RequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*Response `json:"responses"`
+	}
+
+	Parameter struct {
+		Name        string             `json:"name"`
+		In          string             `json:"in"`
+		Description string             `json:"description,omitempty"`
+		Required    bool               `json:"required,omitempty"`
+		Schema      *jsonschema.Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Description string               `json:"description,omitempty"`
+		Required    bool                 `json:"required,omitempty"`
+		Content     map[string]MediaType `json:"content"`
+	}
+
+	Response struct {
+		Description string               `json:"description"`
+		Content     map[string]MediaType `json:"content,omitempty"`
+	}
+
+	MediaType struct {
+		Schema *jsonschema.Schema `json:"schema"`
+	}
+)
+
+// Where params are sent.
+const (
+	InBody  = "body"
+	InQuery = "query"
+)
+
+type Options struct {
+	Info Info
+	// ParamsIn is InBody (the default) to take params as a JSON request
+	// body on POST, or InQuery to take them as query parameters on GET.
+	// Only @readonly queries may run on GET, so the rest stay on POST.
+	ParamsIn string
+}
+
+// Generate builds a document with one operation per query, mounted at
+// /queries/{name}/run, as served by the serve command.
+func Generate(docs []compiler.QueryDoc, opts Options) *Document {
+	if opts.Info.Title == "" {
+		opts.Info.Title = "Queries"
+	}
+	if opts.Info.Version == "" {
+		opts.Info.Version = "0.0.0"
+	}
+	d := &Document{
+		OpenAPI:           Version,
+		Info:              opts.Info,
+		JSONSchemaDialect: jsonschema.Draft,
+		Paths:             make(map[string]*PathItem),
+		Components: Components{SecuritySchemes: map[string]*SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer", Description: "A token from the serve command's -tokens file."},
+		}},
+		// Callers without a token run as anonymous.
+		Security: []map[string][]string{{"bearer": {}}, {}},
+	}
+	for _, q := range docs {
+		op := operation(q)
+		if opts.ParamsIn == InQuery && q.Policy.ReadOnly {
+			op.Parameters = queryParameters(q)
+			d.Paths[Path(q)] = &PathItem{Get: op}
+		} else {
+			op.RequestBody = requestBody(q)
+			d.Paths[Path(q)] = &PathItem{Post: op}
+		}
+	}
+	return d
+}
+
+// Path is where a query's operation is mounted.
+func Path(q compiler.QueryDoc) string {
+	return "/queries/" + q.Name + "/run"
+}
+
+func operation(q compiler.QueryDoc) *Operation {
+	return &Operation{
+		OperationID: q.Name,
+		Summary:     q.Title,
+		Description: q.Description,
+		Responses: map[string]*Response{
+			"200": {
+				Description: responseDescription(q.Output),
+				Content: map[string]MediaType{
+					"application/json": {Schema: ResultSchema(q.Output)},
+				},
+			},
+			"400": errorResponse("The params are invalid; problems lists each one."),
+			"401": errorResponse("The caller couldn't be authenticated."),
+			"403": errorResponse("The caller has none of the roles the query requires."),
+			"404": errorResponse("There is no such query."),
+			"503": errorResponse("No database is configured."),
+			"504": errorResponse("The query took longer than its timeout."),
+		},
+	}
+}
+
+func errorResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Content: map[string]MediaType{
+			"application/json": {Schema: ErrorSchema()},
+		},
+	}
+}
+
+func responseDescription(t compiler.Table) string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return "Query results"
+}
+
+func queryParameters(q compiler.QueryDoc) []Parameter {
+	params := make([]Parameter, 0, len(q.Params))
+	for _, p := range q.Params {
+		schema := jsonschema.ForParam(p)
+		description := schema.Description
+		schema.Description = ""
+		params = append(params, Parameter{
+			Name:        p.ProperName,
+			In:          "query",
+			Description: description,
+			Required:    p.Required,
+			Schema:      schema,
+		})
+	}
+	return params
+}
+
+func requestBody(q compiler.QueryDoc) *RequestBody {
+	schema := jsonschema.ForQuery(q)
+	// The dialect is declared once for the whole document.
+	schema.Schema = ""
+	return &RequestBody{
+		Required: len(schema.Required) > 0,
+		Content: map[string]MediaType{
+			"application/json": {Schema: schema},
+		},
+	}
+}
+
+// ResultSchema describes the result of running a query: its rows, as
+// objects keyed by column, along with the table labeling them. Every
+// column is in each row, but its value may be null, as for SQL NULLs and
+// redacted columns.
+func ResultSchema(t compiler.Table) *jsonschema.Schema {
+	row := &jsonschema.Schema{
+		Type:       "object",
+		Properties: make(map[string]*jsonschema.Schema),
+		Required:   make([]string, 0, len(t.Columns)),
+	}
+	for _, c := range t.Columns {
+		col := jsonschema.Nullable(jsonschema.ForType(c.Type))
+		col.Title = c.Blurb
+		col.Description = c.Description
+		row.Properties[c.ProperName] = col
+		row.Required = append(row.Required, c.ProperName)
+	}
+	column := object(map[string]*jsonschema.Schema{
+		"name":        {Type: "string"},
+		"type":        {Type: "string"},
+		"blurb":       {Type: "string"},
+		"description": {Type: "string"},
+	}, "name")
+	table := object(map[string]*jsonschema.Schema{
+		"title":       {Type: "string"},
+		"description": {Type: "string"},
+		"columns":     {Type: "array", Items: column},
+	}, "title", "description", "columns")
+	return object(map[string]*jsonschema.Schema{
+		"query": {Type: "string"},
+		"table": table,
+		"rows": {
+			Title:       t.Title,
+			Description: t.Description,
+			Type:        "array",
+			Items:       row,
+		},
+		"rowCount":  {Type: "integer"},
+		"truncated": {Type: "boolean", Description: "Rows were dropped to honor the query's row limit."},
+		"redacted":  {Type: "array", Items: &jsonschema.Schema{Type: "string"}, Description: "The sensitive columns whose values were withheld."},
+	}, "query", "table", "rows", "rowCount")
+}
+
+// ErrorSchema describes the body of a failed run.
+func ErrorSchema() *jsonschema.Schema {
+	problem := object(map[string]*jsonschema.Schema{
+		"param":   {Type: "string"},
+		"message": {Type: "string"},
+	}, "param", "message")
+	return object(map[string]*jsonschema.Schema{
+		"error":    {Type: "string"},
+		"problems": {Type: "array", Items: problem},
+	}, "error")
+}
+
+func object(properties map[string]*jsonschema.Schema, required ...string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "object", Properties: properties, Required: required}
+}
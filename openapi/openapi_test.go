@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var orders = compiler.QueryDoc{
+	Name:  "orders",
+	Title: "Orders",
+	Params: []compiler.Param{
+		{ProperName: "status", Type: compiler.String, Required: true},
+	},
+	Output: compiler.Table{
+		Title: "Orders",
+		Columns: []compiler.Column{
+			{ProperName: "id", Type: compiler.Integer, Description: "Order id"},
+		},
+	},
+}
+
+func TestGenerateBody(t *testing.T) {
+	d := Generate([]compiler.QueryDoc{orders}, Options{})
+	item, ok := d.Paths["/queries/orders/run"]
+	if !ok || item.Post == nil {
+		t.Fatalf("Missing POST operation. Got %v", d.Paths)
+	}
+	if item.Post.OperationID != "orders" {
+		t.Errorf("Wrong operation id. Got %v want %v", item.Post.OperationID, "orders")
+	}
+	if item.Post.RequestBody == nil || !item.Post.RequestBody.Required {
+		t.Errorf("Expected a required request body")
+	}
+	result := item.Post.Responses["200"].Content["application/json"].Schema
+	if result.Type != "object" || result.Properties["rowCount"].Type != "integer" {
+		t.Errorf("Wrong result schema. Got %+v", result)
+	}
+	rows := result.Properties["rows"]
+	id := rows.Items.Properties["id"]
+	if rows.Type != "array" || len(id.AnyOf) != 2 || id.AnyOf[0].Type != "integer" || id.AnyOf[1].Type != "null" {
+		t.Errorf("Wrong response schema. Got %+v", rows)
+	}
+	if len(rows.Items.Required) != 1 || rows.Items.Required[0] != "id" {
+		t.Errorf("Wrong required columns. Got %v want %v", rows.Items.Required, []string{"id"})
+	}
+	if rows.Items.Properties["id"].Description != "Order id" {
+		t.Errorf("Wrong column description. Got %v want %v", rows.Items.Properties["id"].Description, "Order id")
+	}
+}
+
+func TestGenerateQuery(t *testing.T) {
+	readOnly := orders
+	readOnly.Name, readOnly.Policy.ReadOnly = "read_orders", true
+	d := Generate([]compiler.QueryDoc{orders, readOnly}, Options{ParamsIn: InQuery})
+	op := d.Paths["/queries/read_orders/run"].Get
+	if op == nil {
+		t.Fatalf("Missing GET operation")
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].In != "query" || !op.Parameters[0].Required {
+		t.Errorf("Wrong parameters. Got %+v", op.Parameters)
+	}
+	if item := d.Paths["/queries/orders/run"]; item.Get != nil || item.Post == nil {
+		t.Errorf("A query that may write wasn't left on POST. Got %+v", item)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	op := Generate([]compiler.QueryDoc{orders}, Options{}).Paths["/queries/orders/run"].Post
+	for _, status := range []string{"400", "401", "403", "404", "504"} {
+		r, ok := op.Responses[status]
+		if !ok {
+			t.Errorf("Missing %v response", status)
+			continue
+		}
+		if r.Content["application/json"].Schema.Properties["error"] == nil {
+			t.Errorf("Wrong %v response schema. Got %+v", status, r.Content["application/json"].Schema)
+		}
+	}
+}
+
+func TestGenerateSecurity(t *testing.T) {
+	d := Generate([]compiler.QueryDoc{orders}, Options{})
+	bearer := d.Components.SecuritySchemes["bearer"]
+	if bearer == nil || bearer.Type != "http" || bearer.Scheme != "bearer" {
+		t.Errorf("Wrong bearer scheme. Got %+v", bearer)
+	}
+	if len(d.Security) != 2 || d.Security[0]["bearer"] == nil || len(d.Security[1]) != 0 {
+		t.Errorf("Wrong security requirements. Got %v", d.Security)
+	}
+}
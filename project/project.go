@@ -0,0 +1,264 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/sidecar"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// cacheFormat changes whenever cached entries can no longer be read.
+const cacheFormat = "2"
+
+// Version identifies the build compiling docs, so a new build doesn't
+// trust what an older one cached. It is empty, and caching off, when the
+// build can't be told apart from others. It's worked out the first time a
+// cache is used, since that may mean hashing the executable.
+func Version() string {
+	return version()
+}
+
+var version = sync.OnceValue(buildVersion)
+
+// buildVersion is the VCS revision of a clean build. Builds without one,
+// or with local changes, are identified by a hash of their executable
+// instead, since "(devel)" and the like are shared by every such build.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		revision, modified := "", false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if revision != "" && !modified {
+			return info.Main.Version + " " + revision
+		}
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return "exe " + hex.EncodeToString(h.Sum(nil))
+}
+
+// Options tune Compile. The zero value compiles on every CPU without a
+// cache.
+type Options struct {
+	Workers int
+	// CacheDir holds compiled docs keyed by file content, skipped when
+	// empty.
+	CacheDir string
+}
+
+// Compiler compiles the .sql files of a project concurrently.
+type Compiler struct {
+	Options
+}
+
+func New(opts Options) *Compiler {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.CacheDir != "" && Version() == "" {
+		opts.CacheDir = ""
+	}
+	return &Compiler{Options: opts}
+}
+
+// Compile compiles every path into a catalog like catalog.Load does,
+// docs ordered by file as given.
+func (c *Compiler) Compile(paths ...string) (*catalog.Catalog, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		found, err := catalog.SQLFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+	results := make([]result, len(files))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < c.Workers && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i].docs, results[i].err = c.CompileFile(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	cat := catalog.New(nil)
+	for i, r := range results {
+		if r.err != nil {
+			return nil, fmt.Errorf("%s: %v", files[i], r.err)
+		}
+		cat.Add(files[i], r.docs)
+	}
+	if err := cat.Resolve(); err != nil {
+		return nil, err
+	}
+	return cat, cat.CheckNames()
+}
+
+type result struct {
+	docs []compiler.QueryDoc
+	err  error
+}
+
+// CompileFile compiles one .sql file, merging in its sidecar if it has
+// one.
+func (c *Compiler) CompileFile(path string) ([]compiler.QueryDoc, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := Key(src)
+	docs, ok := c.load(key)
+	if !ok {
+		docs, err = catalog.Compile(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, docs)
+	}
+	return applySidecar(path, src, docs)
+}
+
+// applySidecar merges the docs in path's sidecar, if it has one, into
+// those compiled from its doc comments.
+func applySidecar(path string, src []byte, docs []compiler.QueryDoc) ([]compiler.QueryDoc, error) {
+	name := sidecar.Path(path)
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return docs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	side, err := sidecar.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	statements, err := tokenizer.Statements(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	docs, err = side.Apply(statements, docs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return docs, nil
+}
+
+// Key is what a file's compiled docs are cached under: a hash of its
+// content and the build compiling it.
+func Key(src []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", cacheFormat, Version())
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// entry is a cached file. Positions and whether each doc was named
+// aren't part of a doc's JSON so they are kept alongside.
+type entry struct {
+	Docs      []compiler.QueryDoc     `json:"docs"`
+	Positions [][2]tokenizer.Position `json:"positions"`
+	Named     []bool                  `json:"named"`
+}
+
+func (c *Compiler) path(key string) string {
+	return filepath.Join(c.CacheDir, key[:2], key+".json")
+}
+
+// load reads a cache entry. Anything unreadable is a miss.
+func (c *Compiler) load(key string) ([]compiler.QueryDoc, bool) {
+	if c.CacheDir == "" {
+		return nil, false
+	}
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e entry
+	if json.Unmarshal(b, &e) != nil || len(e.Positions) != len(e.Docs) || len(e.Named) != len(e.Docs) {
+		return nil, false
+	}
+	for i := range e.Docs {
+		e.Docs[i].Rehydrate()
+		e.Docs[i].Pos, e.Docs[i].End = e.Positions[i][0], e.Positions[i][1]
+		e.Docs[i].Named = e.Named[i]
+	}
+	return e.Docs, true
+}
+
+// store writes a cache entry, ignoring failures since the cache is only
+// an optimization.
+func (c *Compiler) store(key string, docs []compiler.QueryDoc) {
+	if c.CacheDir == "" {
+		return
+	}
+	e := entry{Docs: docs, Positions: make([][2]tokenizer.Position, 0, len(docs)), Named: make([]bool, 0, len(docs))}
+	for _, doc := range docs {
+		e.Positions = append(e.Positions, [2]tokenizer.Position{doc.Pos, doc.End})
+		e.Named = append(e.Named, doc.Named)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	path := c.path(key)
+	if os.MkdirAll(filepath.Dir(path), 0o755) != nil {
+		return
+	}
+	// Write then rename so concurrent runs never read half an entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,103 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const orders = `/**
+@title "Orders"
+@description "All *orders*."
+*/
+SELECT * FROM orders;`
+
+const users = `/**
+@title "Users"
+*/
+SELECT * FROM users;`
+
+func TestCompile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.sql"), []byte(orders), 0o644)
+	os.Mkdir(filepath.Join(dir, "nested"), 0o755)
+	os.WriteFile(filepath.Join(dir, "nested", "b.sql"), []byte(users), 0o644)
+	cacheDir := t.TempDir()
+	c := New(Options{Workers: 2, CacheDir: cacheDir})
+	cat, err := c.Compile(dir)
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if len(cat.Docs) != 2 || cat.Docs[0].Name != "orders" || cat.Docs[1].Name != "users" {
+		t.Fatalf("Wrong docs. Got %v", cat.Docs)
+	}
+	// Doctor the cached entry to show the second compile reads it.
+	key := Key([]byte(orders))
+	path := c.path(key)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected a cache entry. Got %v", err)
+	}
+	os.WriteFile(path, []byte(strings.Replace(string(b), `"title":"Orders"`, `"title":"Cached"`, 1)), 0o644)
+	cat, err = c.Compile(dir)
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	doc := cat.Docs[0]
+	if doc.Title != "Cached" {
+		t.Errorf("Expected the cached doc. Got title %v", doc.Title)
+	}
+	if doc.Markup == nil || doc.Pos.Line != 1 || doc.End.Line != 5 {
+		t.Errorf("Cached doc wasn't restored. Got markup %v, pos %v, end %v", doc.Markup, doc.Pos, doc.End)
+	}
+	if doc.Source != filepath.Join(dir, "a.sql") {
+		t.Errorf("Wrong source. Got %v", doc.Source)
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "gen.sql"), []byte("SELECT 1;\n"+orders), 0o644)
+	os.WriteFile(filepath.Join(dir, "gen.sql.docs.yaml"), []byte("statements:\n  - index: 1\n    name: one\n    title: One\n"), 0o644)
+	c := New(Options{CacheDir: t.TempDir()})
+	for i := 0; i < 2; i++ {
+		cat, err := c.Compile(dir)
+		if err != nil {
+			t.Fatalf("Unexpected err %v", err)
+		}
+		if len(cat.Docs) != 2 || cat.Docs[0].Name != "one" || cat.Docs[0].SQL != "SELECT 1" || cat.Docs[1].Name != "orders" {
+			t.Errorf("Wrong docs on compile %d. Got %+v", i+1, cat.Docs)
+		}
+	}
+}
+
+func TestSidecarConflictCached(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "q.sql"), []byte("/**\n@name orders\n@title \"Orders\"\n*/\nSELECT 1;"), 0o644)
+	os.WriteFile(filepath.Join(dir, "q.sql.docs.yaml"), []byte("statements:\n  - index: 1\n    name: other\n"), 0o644)
+	c := New(Options{CacheDir: t.TempDir()})
+	for i := 0; i < 2; i++ {
+		_, err := c.Compile(dir)
+		if err == nil || !strings.Contains(err.Error(), "name conflicts") {
+			t.Errorf("Expected a name conflict on compile %d. Got %v", i+1, err)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	// Test binaries carry no VCS stamp, so they're told apart by hash.
+	if v := Version(); !strings.HasPrefix(v, "exe ") {
+		t.Errorf("Wrong version for an unstamped build. Got %q", v)
+	}
+	defer func(v func() string) { version = v }(version)
+	version = func() string {
+		t.Error("Version was worked out without a cache.")
+		return ""
+	}
+	New(Options{})
+	version = func() string { return "" }
+	if c := New(Options{CacheDir: t.TempDir()}); c.CacheDir != "" {
+		t.Errorf("Cache wasn't turned off for an unidentified build. Got %v", c.CacheDir)
+	}
+}
@@ -0,0 +1,57 @@
+package render
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/markdown"
+)
+
+var funcs = template.FuncMap{
+	// The markdown renderer escapes all text itself, so its output is safe
+	// to splice into the page as is.
+	"markup": func(doc *markdown.Document) template.HTML {
+		return template.HTML(doc.HTML())
+	},
+}
+
+var page = template.Must(template.New("page").Funcs(funcs).Parse(`<meta charset="UTF-8">
+{{- range .}}
+<section>
+<div><h1>{{.Title}}</h1></div>
+{{- with .Deprecated}}
+<p><strong>Deprecated:</strong> {{.Reason}}{{with .ReplacedBy}} Use <code>{{.}}</code> instead.{{end}}</p>
+{{- end}}
+{{- with .Markup}}
+<div>{{markup .}}</div>
+{{- end}}
+{{- if .Params}}
+<h2>Parameters</h2>
+<dl>
+{{- range .Params}}
+<dt><code>{{.ProperName}}</code> {{.Blurb}}</dt>
+<dd>{{markup .Markup}}</dd>
+{{- end}}
+</dl>
+{{- end}}
+{{- with .Output}}{{if or .Title .Columns}}
+<h2>{{.Title}}</h2>
+{{- with .Markup}}
+<div>{{markup .}}</div>
+{{- end}}
+<dl>
+{{- range .Columns}}
+<dt><code>{{.ProperName}}</code> {{.Blurb}}</dt>
+<dd>{{markup .Markup}}</dd>
+{{- end}}
+</dl>
+{{- end}}{{end}}
+</section>
+{{- end}}
+`))
+
+// HTML writes docs as a single HTML page.
+func HTML(w io.Writer, docs []compiler.QueryDoc) error {
+	return page.Execute(w, docs)
+}
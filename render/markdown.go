@@ -0,0 +1,69 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/markdown"
+)
+
+// Markdown writes docs as a single Markdown document.
+func Markdown(w io.Writer, docs []compiler.QueryDoc) error {
+	b := bufio.NewWriter(w)
+	for i, doc := range docs {
+		if i > 0 {
+			fmt.Fprint(b, "\n")
+		}
+		fmt.Fprintf(b, "# %s\n", text(doc.Title))
+		if d := doc.Deprecated; d != nil {
+			fmt.Fprintf(b, "\n> **Deprecated:** %s", text(d.Reason))
+			if d.ReplacedBy != "" {
+				fmt.Fprintf(b, " Use %s instead.", markdown.CodeSpan(d.ReplacedBy))
+			}
+			fmt.Fprint(b, "\n")
+		}
+		writeDescription(b, doc.Markup)
+		if len(doc.Params) > 0 {
+			fmt.Fprint(b, "\n## Parameters\n\n")
+			for _, p := range doc.Params {
+				writeItem(b, p.ProperName, p.Blurb, p.Markup)
+			}
+		}
+		if doc.Output.Title != "" || len(doc.Output.Columns) > 0 {
+			fmt.Fprintf(b, "\n## %s\n", text(doc.Output.Title))
+			writeDescription(b, doc.Output.Markup)
+			if len(doc.Output.Columns) > 0 {
+				fmt.Fprint(b, "\n")
+			}
+			for _, c := range doc.Output.Columns {
+				writeItem(b, c.ProperName, c.Blurb, c.Markup)
+			}
+		}
+	}
+	return b.Flush()
+}
+
+// text escapes plain text from a doc, keeping it on one line.
+func text(s string) string {
+	return markdown.Escape(strings.Join(strings.Fields(s), " "))
+}
+
+func writeDescription(w io.Writer, doc *markdown.Document) {
+	if text := doc.Markdown(); text != "" {
+		fmt.Fprintf(w, "\n%s\n", text)
+	}
+}
+
+func writeItem(w io.Writer, name, blurb string, doc *markdown.Document) {
+	fmt.Fprintf(w, "- %s **%s**", markdown.CodeSpan(name), text(blurb))
+	text := doc.Markdown()
+	if text == "" {
+		fmt.Fprint(w, "\n")
+		return
+	}
+	// Nest the description under the list item.
+	fmt.Fprintf(w, "\n\n  %s\n", strings.ReplaceAll(text, "\n", "\n  "))
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func docs() []compiler.QueryDoc {
+	q := compiler.QueryDoc{
+		Name:        "orders",
+		Title:       "Orders <b>& *stars*",
+		Description: "All *orders*, `including` <script>alert(1)</script>.",
+		Deprecated:  &compiler.Deprecation{Reason: "Slow <i>_here_</i>.", ReplacedBy: "fast`orders"},
+		Params: []compiler.Param{
+			{ProperName: "status", Blurb: "Status [sic]", Description: "One of:\n\n- open\n- closed"},
+			{ProperName: "limit", Blurb: "Limit"},
+		},
+		Output: compiler.Table{
+			Title:       "Rows\nof orders",
+			Description: "A row per **order**.",
+			Columns: []compiler.Column{
+				{ProperName: "id", Blurb: "ID", Description: "The order's <id>."},
+				{ProperName: "note", Blurb: "Note & more"},
+			},
+		},
+	}
+	bare := compiler.QueryDoc{Name: "bare", Title: "Bare"}
+	return []compiler.QueryDoc{rehydrated(q), bare}
+}
+
+// rehydrated parses q's descriptions, as compiling would.
+func rehydrated(q compiler.QueryDoc) compiler.QueryDoc {
+	q.Rehydrate()
+	return q
+}
+
+func golden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%v doesn't match. Got\n%s\nwant\n%s", name, got, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	b := bytes.Buffer{}
+	if err := HTML(&b, docs()); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "docs.html", b.Bytes())
+}
+
+func TestMarkdown(t *testing.T) {
+	b := bytes.Buffer{}
+	if err := Markdown(&b, docs()); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "docs.md", b.Bytes())
+}
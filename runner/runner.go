@@ -0,0 +1,388 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/christopher-henderson/DocStringParser/bind"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+)
+
+// DB is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type DB interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// beginner is a DB that can start transactions, as *sql.DB and *sql.Conn
+// can but *sql.Tx can't.
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Runner executes documented queries against a database, honoring each
+// query's policy.
+type Runner struct {
+	DB          DB
+	Placeholder bind.Style
+
+	mu    sync.Mutex
+	cache map[string]cached
+	// nextExpiry is when the soonest cached result expires, for store to
+	// sweep expired results by.
+	nextExpiry time.Time
+}
+
+type cached struct {
+	result  *Result
+	expires time.Time
+}
+
+func New(db DB, driver string) *Runner {
+	return &Runner{DB: db, Placeholder: bind.StyleFor(driver)}
+}
+
+// ValidationError lists every problem with the values supplied for a
+// query's params.
+type ValidationError struct {
+	Problems []Problem `json:"problems"`
+}
+
+type Problem struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		msgs = append(msgs, p.Param+": "+p.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Result is a query's rows, shaped and labeled by its output table.
+type Result struct {
+	Query    string                   `json:"query"`
+	Table    Table                    `json:"table"`
+	Rows     []map[string]interface{} `json:"rows"`
+	RowCount int                      `json:"rowCount"`
+	// Truncated is set when rows were dropped to honor @maxrows.
+	Truncated bool `json:"truncated,omitempty"`
+	// Redacted names the sensitive columns whose values were withheld.
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+type Table struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Columns     []Column `json:"columns"`
+}
+
+type Column struct {
+	Name        string        `json:"name"`
+	Type        compiler.Type `json:"type,omitempty"`
+	Blurb       string        `json:"blurb,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// Values validates values against the query's params, applying defaults,
+// and returns the value for each param by name. Values may be strings or
+// JSON scalars.
+func Values(q compiler.QueryDoc, values map[string]interface{}) (map[string]interface{}, error) {
+	bound := make(map[string]interface{})
+	problems := make([]Problem, 0)
+	declared := make(map[string]bool)
+	for _, p := range q.Params {
+		declared[p.ProperName] = true
+		raw, ok := values[p.ProperName]
+		if !ok || raw == nil {
+			switch {
+			case p.Default != nil:
+				v, err := p.Type.Value(*p.Default)
+				if err != nil {
+					return nil, fmt.Errorf("bad default for param %v: %v", p.ProperName, err)
+				}
+				bound[p.ProperName] = v
+			case p.Required:
+				problems = append(problems, Problem{p.ProperName, "is required"})
+			default:
+				bound[p.ProperName] = nil
+			}
+			continue
+		}
+		s, err := literal(raw)
+		if err != nil {
+			problems = append(problems, Problem{p.ProperName, err.Error()})
+			continue
+		}
+		v, err := p.Type.Value(s)
+		if err != nil {
+			problems = append(problems, Problem{p.ProperName, fmt.Sprintf("%q is not a valid %v", s, p.Type)})
+			continue
+		}
+		if len(p.Enum) > 0 && !contains(p.Enum, s) {
+			problems = append(problems, Problem{p.ProperName, fmt.Sprintf("must be one of %v", strings.Join(p.Enum, ", "))})
+			continue
+		}
+		bound[p.ProperName] = v
+	}
+	unknown := make([]string, 0)
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, Problem{name, "is not a param of " + q.Name})
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return bound, nil
+}
+
+func literal(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return fmt.Sprint(v), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("must be a scalar, got %T", v)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Run validates values and executes the query. Results of queries with
+// a cache policy are reused until they expire.
+func (r *Runner) Run(ctx context.Context, q compiler.QueryDoc, values map[string]interface{}) (*Result, error) {
+	bound, err := Values(q, values)
+	if err != nil {
+		return nil, err
+	}
+	key := ""
+	if q.Policy.Cache > 0 {
+		b, err := json.Marshal(bound)
+		if err != nil {
+			return nil, err
+		}
+		// The statement is part of the key so edited queries aren't
+		// answered from the old one's results.
+		key = q.Name + "\x00" + q.SQL + "\x00" + string(b)
+		if result, ok := r.cached(key); ok {
+			return result, nil
+		}
+	}
+	var result *Result
+	err = r.query(ctx, q, bound, func(rows *Rows) (err error) {
+		result, err = shape(q, rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		r.store(key, result, q.Policy.Cache)
+	}
+	return result, nil
+}
+
+// Rows are a query's rows, ending early after the query's @maxrows.
+type Rows struct {
+	*sql.Rows
+	max, read int
+	// Truncated is set once Next finds a row past the limit.
+	Truncated bool
+}
+
+func (r *Rows) Next() bool {
+	if r.max > 0 && r.read == r.max {
+		r.Truncated = r.Truncated || r.Rows.Next()
+		return false
+	}
+	if !r.Rows.Next() {
+		return false
+	}
+	r.read++
+	return true
+}
+
+// Query validates values and executes the query, passing its rows to
+// read. The rows are closed once read returns.
+func (r *Runner) Query(ctx context.Context, q compiler.QueryDoc, values map[string]interface{}, read func(*Rows) error) error {
+	bound, err := Values(q, values)
+	if err != nil {
+		return err
+	}
+	return r.query(ctx, q, bound, read)
+}
+
+func (r *Runner) query(ctx context.Context, q compiler.QueryDoc, bound map[string]interface{}, read func(*Rows) error) error {
+	// The keyword check only fails early; what keeps a @readonly query
+	// from writing is the read-only transaction it runs in.
+	if q.Policy.ReadOnly && !compiler.IsReadOnly(q.SQL) {
+		return fmt.Errorf("%v is @readonly but its statement can write", q.Name)
+	}
+	statement, refs, err := bind.Rewrite(q.SQL, r.Placeholder)
+	if err != nil {
+		return err
+	}
+	args := make([]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		v, ok := bound[ref]
+		if !ok {
+			return fmt.Errorf("${%s} is not a documented @param", ref)
+		}
+		args = append(args, v)
+	}
+	if q.Policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.Policy.Timeout)
+		defer cancel()
+	}
+	return r.rows(ctx, q.Policy, statement, args, read)
+}
+
+// rows runs statement, in a read-only transaction that is always rolled
+// back if the policy is read only and the DB can begin one. Drivers that
+// don't enforce read-only transactions, like SQLite's, still have
+// anything written undone. A DB that is already a transaction runs the
+// statement as is.
+func (r *Runner) rows(ctx context.Context, policy compiler.Policy, statement string, args []interface{}, read func(*Rows) error) error {
+	db := r.DB
+	if b, ok := db.(beginner); ok && policy.ReadOnly {
+		tx, err := b.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		db = tx
+	}
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return read(&Rows{Rows: rows, max: policy.MaxRows})
+}
+
+func (r *Runner) cached(key string) (*Result, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.cache[key]
+	if !ok || time.Now().After(c.expires) {
+		delete(r.cache, key)
+		return nil, false
+	}
+	return c.result, true
+}
+
+// store caches a result, first dropping any that expired, since keys
+// include the values bound and so may never be looked up again.
+func (r *Runner) store(key string, result *Result, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = make(map[string]cached)
+	}
+	now := time.Now()
+	if len(r.cache) > 0 && now.After(r.nextExpiry) {
+		r.nextExpiry = time.Time{}
+		for k, c := range r.cache {
+			if now.After(c.expires) {
+				delete(r.cache, k)
+			} else if r.nextExpiry.IsZero() || c.expires.Before(r.nextExpiry) {
+				r.nextExpiry = c.expires
+			}
+		}
+	}
+	c := cached{result: result, expires: now.Add(ttl)}
+	if len(r.cache) == 0 || c.expires.Before(r.nextExpiry) {
+		r.nextExpiry = c.expires
+	}
+	r.cache[key] = c
+}
+
+// shape reads rows into objects keyed by column. When the output table
+// documents columns only those are kept, in documented order.
+func shape(q compiler.QueryDoc, rows *Rows) (*Result, error) {
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Query: q.Name,
+		Table: Table{Title: q.Output.Title, Description: q.Output.Description, Columns: make([]Column, 0)},
+		Rows:  make([]map[string]interface{}, 0),
+	}
+	documented := len(q.Output.Columns) > 0
+	if documented {
+		for _, c := range q.Output.Columns {
+			result.Table.Columns = append(result.Table.Columns, Column{
+				Name:        c.ProperName,
+				Type:        c.Type,
+				Blurb:       c.Blurb,
+				Description: c.Description,
+			})
+		}
+	} else {
+		for _, name := range names {
+			result.Table.Columns = append(result.Table.Columns, Column{Name: name})
+		}
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		ptrs := make([]interface{}, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		err := rows.Scan(ptrs...)
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(result.Table.Columns))
+		if documented {
+			for _, c := range result.Table.Columns {
+				row[c.Name] = nil
+			}
+		}
+		for i, name := range names {
+			if _, ok := row[name]; documented && !ok {
+				continue
+			}
+			row[name] = jsonValue(values[i])
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.RowCount = len(result.Rows)
+	result.Truncated = rows.Truncated
+	return result, rows.Err()
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return v
+}
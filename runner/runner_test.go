@@ -0,0 +1,251 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	_ "modernc.org/sqlite"
+)
+
+var ten = "10"
+
+var orders = compiler.QueryDoc{
+	Name: "orders",
+	Params: []compiler.Param{
+		{ProperName: "status", Type: compiler.String, Required: true, Enum: []string{"open", "closed"}},
+		{ProperName: "limit", Type: compiler.Integer, Default: &ten},
+		{ProperName: "since", Type: compiler.Date},
+	},
+}
+
+func TestValues(t *testing.T) {
+	bound, err := Values(orders, map[string]interface{}{"status": "open", "since": "2020-01-02"})
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if bound["status"] != "open" || bound["limit"] != int64(10) || bound["since"] != "2020-01-02" {
+		t.Errorf("Wrong values. Got %v", bound)
+	}
+	bound, err = Values(orders, map[string]interface{}{"status": "closed", "limit": json.Number("5")})
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if bound["limit"] != int64(5) || bound["since"] != nil {
+		t.Errorf("Wrong values. Got %v", bound)
+	}
+}
+
+func TestValuesInvalid(t *testing.T) {
+	_, err := Values(orders, map[string]interface{}{"limit": "many", "since": "yesterday", "extra": true})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Wrong error. Got %v want a ValidationError", err)
+	}
+	want := []string{"status", "limit", "since", "extra"}
+	if len(invalid.Problems) != len(want) {
+		t.Fatalf("Wrong problems. Got %v want problems with %v", invalid.Problems, want)
+	}
+	for i, p := range invalid.Problems {
+		if p.Param != want[i] {
+			t.Errorf("Wrong problem at %v. Got %v want %v", i, p.Param, want[i])
+		}
+	}
+}
+
+// openDB opens an in-memory SQLite database of a few orders.
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`CREATE TABLE orders (id INTEGER, status TEXT, total REAL);
+		INSERT INTO orders VALUES (1, 'open', 9.5), (2, 'closed', 20), (3, 'open', NULL);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRun(t *testing.T) {
+	q := compiler.QueryDoc{
+		Name:   "open_orders",
+		SQL:    "SELECT id, total, status AS ignored FROM orders WHERE status = ${status} ORDER BY id",
+		Params: []compiler.Param{{ProperName: "status", Type: compiler.String, Required: true}},
+		Output: compiler.Table{Title: "Orders", Columns: []compiler.Column{
+			{ProperName: "id", Type: compiler.Integer, Blurb: "ID"},
+			{ProperName: "total", Type: compiler.Number},
+			{ProperName: "note", Type: compiler.String},
+		}},
+	}
+	result, err := New(openDB(t), "sqlite").Run(context.Background(), q, map[string]interface{}{"status": "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Query != "open_orders" || result.Table.Title != "Orders" || len(result.Table.Columns) != 3 || result.Table.Columns[0].Blurb != "ID" {
+		t.Errorf("Wrong result table. Got %+v", result.Table)
+	}
+	if result.RowCount != 2 || result.Rows[0]["id"] != int64(1) || result.Rows[0]["total"] != 9.5 || result.Rows[1]["total"] != nil {
+		t.Errorf("Wrong rows. Got %v", result.Rows)
+	}
+	// Undocumented columns are dropped and documented ones not returned
+	// are null.
+	if _, ok := result.Rows[0]["ignored"]; ok {
+		t.Errorf("Undocumented column was kept. Got %v", result.Rows[0])
+	}
+	if v, ok := result.Rows[0]["note"]; !ok || v != nil {
+		t.Errorf("Documented column missing from row. Got %v", result.Rows[0])
+	}
+	_, err = New(openDB(t), "sqlite").Run(context.Background(), q, nil)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Errorf("Wrong error for a missing param. Got %v want a ValidationError", err)
+	}
+}
+
+func TestShapeUndocumented(t *testing.T) {
+	q := compiler.QueryDoc{Name: "statuses", SQL: "SELECT DISTINCT status, count(*) AS n FROM orders GROUP BY status ORDER BY status"}
+	result, err := New(openDB(t), "sqlite").Run(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Table.Columns) != 2 || result.Table.Columns[0].Name != "status" || result.Table.Columns[1].Name != "n" {
+		t.Errorf("Wrong columns. Got %+v", result.Table.Columns)
+	}
+	if result.RowCount != 2 || result.Rows[0]["status"] != "closed" || result.Rows[1]["n"] != int64(2) {
+		t.Errorf("Wrong rows. Got %v", result.Rows)
+	}
+}
+
+func TestMaxRows(t *testing.T) {
+	q := compiler.QueryDoc{Name: "all", SQL: "SELECT id FROM orders ORDER BY id", Policy: compiler.Policy{MaxRows: 2}}
+	r := New(openDB(t), "sqlite")
+	result, err := r.Run(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowCount != 2 || !result.Truncated {
+		t.Errorf("Rows weren't truncated. Got %v rows, truncated %v", result.RowCount, result.Truncated)
+	}
+	read := 0
+	err = r.Query(context.Background(), q, nil, func(rows *Rows) error {
+		for rows.Next() {
+			read++
+		}
+		if !rows.Truncated {
+			t.Errorf("Query rows weren't marked truncated")
+		}
+		return rows.Err()
+	})
+	if err != nil || read != 2 {
+		t.Errorf("Query read past @maxrows. Got %v rows, err %v want 2", read, err)
+	}
+	q.Policy.MaxRows = 3
+	result, err = r.Run(context.Background(), q, nil)
+	if err != nil || result.RowCount != 3 || result.Truncated {
+		t.Errorf("Exactly @maxrows rows shouldn't be truncated. Got %v rows, truncated %v, err %v", result.RowCount, result.Truncated, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	q := compiler.QueryDoc{
+		Name:   "slow",
+		SQL:    "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n",
+		Policy: compiler.Policy{Timeout: 10 * time.Millisecond},
+	}
+	_, err := New(openDB(t), "sqlite").Run(context.Background(), q, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wrong error. Got %v want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCache(t *testing.T) {
+	db := openDB(t)
+	q := compiler.QueryDoc{Name: "count", SQL: "SELECT count(*) AS n FROM orders", Policy: compiler.Policy{Cache: 50 * time.Millisecond}}
+	r := New(db, "sqlite")
+	count := func() interface{} {
+		result, err := r.Run(context.Background(), q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Rows[0]["n"]
+	}
+	if n := count(); n != int64(3) {
+		t.Fatalf("Wrong count. Got %v want 3", n)
+	}
+	if _, err := db.Exec("INSERT INTO orders VALUES (4, 'open', 1)"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != int64(3) {
+		t.Errorf("Result wasn't cached. Got %v want 3", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if n := count(); n != int64(4) {
+		t.Errorf("Cached result outlived its TTL. Got %v want 4", n)
+	}
+}
+
+func TestCacheSweeps(t *testing.T) {
+	db := openDB(t)
+	q := compiler.QueryDoc{
+		Name:   "by_id",
+		Params: []compiler.Param{{ProperName: "id", Type: compiler.Integer}},
+		SQL:    "SELECT status FROM orders WHERE id = ${id}",
+		Policy: compiler.Policy{Cache: 20 * time.Millisecond},
+	}
+	r := New(db, "sqlite")
+	for id := 1; id <= 3; id++ {
+		if _, err := r.Run(context.Background(), q, map[string]interface{}{"id": fmt.Sprint(id)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := r.Run(context.Background(), q, map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.cache) != 1 {
+		t.Errorf("Expired results weren't swept. Got %v cached want 1", len(r.cache))
+	}
+}
+
+func TestReadOnlyRollsBack(t *testing.T) {
+	db := openDB(t)
+	r := New(db, "sqlite")
+	// A statement the keyword check would have stopped, run as if it got
+	// past it.
+	policy := compiler.Policy{ReadOnly: true}
+	err := r.rows(context.Background(), policy, "DELETE FROM orders RETURNING id", nil, func(rows *Rows) error {
+		for rows.Next() {
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM orders").Scan(&n); err != nil || n != 3 {
+		t.Errorf("Write in a read-only query wasn't undone. Got %v rows, err %v want 3", n, err)
+	}
+	q := compiler.QueryDoc{Name: "wipe", SQL: "DELETE FROM orders", Policy: policy}
+	if _, err := r.Run(context.Background(), q, nil); err == nil {
+		t.Errorf("Expected the keyword check to fail early")
+	}
+}
+
+func TestValuesBadDefault(t *testing.T) {
+	soon := "soon"
+	q := compiler.QueryDoc{Name: "q", Params: []compiler.Param{{ProperName: "n", Type: compiler.Integer, Default: &soon}}}
+	_, err := Values(q, nil)
+	var invalid *ValidationError
+	if err == nil || errors.As(err, &invalid) {
+		t.Errorf("Expected an error for the doc's bad default, not the caller's values. Got %v", err)
+	}
+}
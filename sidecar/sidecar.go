@@ -0,0 +1,409 @@
+// Package sidecar documents SQL that can't carry doc comments of its own,
+// such as generated or vendored files, from a YAML file beside it.
+//
+// A query.sql is documented by query.sql.docs.yaml:
+//
+//	statements:
+//	  - index: 1
+//	    name: active_users
+//	    title: Active users
+//	    params:
+//	      - name: since
+//	        type: date
+//	        blurb: Since
+//	        description: Only users active since this day.
+//	    table:
+//	      columns:
+//	        - name: id
+//	          type: integer
+//	          blurb: ID
+//	          description: The user's ID.
+//	  - name: orders_by_status
+//	    description: Adds to the doc comment of the query named orders_by_status.
+//
+// Statements are picked by their 1 based index in the file or by the
+// @name of their doc comment.
+package sidecar
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// Path is where the sidecar of a .sql file is.
+func Path(sqlPath string) string {
+	return sqlPath + ".docs.yaml"
+}
+
+// File is a parsed sidecar.
+type File struct {
+	Statements []Entry
+}
+
+// Entry documents one statement.
+type Entry struct {
+	Index       int
+	Name        string
+	Title       string
+	Description string
+	Params      []compiler.Param
+	Table       *compiler.Table
+	Pos         tokenizer.Position
+	// lines are where each param and column was declared, by name.
+	lines map[string]int
+}
+
+// Parse reads a sidecar. Malformed input is reported as Diagnostics
+// positioned in the sidecar.
+func Parse(r io.Reader) (*File, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseYAML(string(src))
+	if err != nil {
+		return nil, compiler.AsDiagnostics(err)
+	}
+	d := decoder{}
+	f := &File{}
+	for _, p := range d.pairs(root) {
+		switch p.key {
+		case "statements":
+			for _, item := range d.items(p.value) {
+				f.Statements = append(f.Statements, d.entry(item))
+			}
+		default:
+			d.unknown(p)
+		}
+	}
+	if len(d.diags) > 0 {
+		return nil, d.diags
+	}
+	return f, nil
+}
+
+// decoder turns parsed YAML into entries, collecting every problem.
+type decoder struct {
+	diags compiler.Diagnostics
+}
+
+func (d *decoder) errorf(line int, format string, args ...interface{}) {
+	d.diags = append(d.diags, compiler.AsDiagnostics(errorAt(line, format, args...))...)
+}
+
+func (d *decoder) unknown(p pair) {
+	d.errorf(p.line, "unknown key %v", p.key)
+}
+
+func (d *decoder) pairs(n *node) []pair {
+	if n.kind == scalarNode && n.value == "" {
+		return nil
+	}
+	if n.kind != mapNode {
+		d.errorf(n.line, "expected a mapping")
+		return nil
+	}
+	return n.pairs
+}
+
+func (d *decoder) items(n *node) []*node {
+	if n.kind == scalarNode && n.value == "" {
+		return nil
+	}
+	if n.kind != seqNode {
+		d.errorf(n.line, "expected a sequence")
+		return nil
+	}
+	return n.items
+}
+
+func (d *decoder) string(n *node) string {
+	if n.kind != scalarNode {
+		d.errorf(n.line, "expected a string")
+	}
+	return n.value
+}
+
+func (d *decoder) strings(n *node) []string {
+	values := make([]string, 0)
+	for _, item := range d.items(n) {
+		values = append(values, d.string(item))
+	}
+	return values
+}
+
+func (d *decoder) bool(n *node) bool {
+	b, err := strconv.ParseBool(n.value)
+	if n.kind != scalarNode || err != nil {
+		d.errorf(n.line, "expected true or false")
+	}
+	return b
+}
+
+func (d *decoder) typ(n *node) compiler.Type {
+	if n.value == "" {
+		return compiler.String
+	}
+	t, err := compiler.ParseType(d.string(n))
+	if err != nil {
+		d.errorf(n.line, "%v", err)
+	}
+	return t
+}
+
+func (d *decoder) entry(n *node) Entry {
+	e := Entry{Pos: n.pos(), lines: make(map[string]int)}
+	for _, p := range d.pairs(n) {
+		switch p.key {
+		case "index":
+			i, err := strconv.Atoi(p.value.value)
+			if p.value.kind != scalarNode || err != nil || i < 1 {
+				d.errorf(p.line, "index must be a statement number from 1")
+			}
+			e.Index = i
+		case "name":
+			e.Name = d.string(p.value)
+		case "title":
+			e.Title = d.string(p.value)
+		case "description":
+			e.Description = d.string(p.value)
+		case "params":
+			for _, item := range d.items(p.value) {
+				param := d.param(item)
+				e.lines["param "+param.ProperName] = item.line
+				e.Params = append(e.Params, param)
+			}
+		case "table":
+			t := compiler.NewTable()
+			for _, tp := range d.pairs(p.value) {
+				switch tp.key {
+				case "title":
+					t.Title = d.string(tp.value)
+				case "description":
+					t.Description = d.string(tp.value)
+				case "columns":
+					for _, item := range d.items(tp.value) {
+						c := d.column(item)
+						e.lines["column "+c.ProperName] = item.line
+						t.Columns = append(t.Columns, c)
+					}
+				default:
+					d.unknown(tp)
+				}
+			}
+			e.Table = &t
+		default:
+			d.unknown(p)
+		}
+	}
+	if e.Index == 0 && e.Name == "" {
+		d.errorf(n.line, "a statement needs an index or a name")
+	}
+	return e
+}
+
+func (d *decoder) param(n *node) compiler.Param {
+	p := compiler.Param{Type: compiler.String}
+	var def *node
+	for _, f := range d.pairs(n) {
+		switch f.key {
+		case "name":
+			p.ProperName = d.string(f.value)
+		case "type":
+			p.Type = d.typ(f.value)
+		case "blurb":
+			p.Blurb = d.string(f.value)
+		case "description":
+			p.Description = d.string(f.value)
+		case "required":
+			p.Required = d.bool(f.value)
+		case "default":
+			def = f.value
+		case "enum":
+			p.Enum = d.strings(f.value)
+		default:
+			d.unknown(f)
+		}
+	}
+	if p.ProperName == "" {
+		d.errorf(n.line, "a param needs a name")
+	}
+	if def != nil {
+		value := d.string(def)
+		if _, err := p.Type.Value(value); err != nil {
+			d.errorf(def.line, "bad default for param %v: %v", p.ProperName, err)
+		}
+		p.Default = &value
+	}
+	for _, value := range p.Enum {
+		if _, err := p.Type.Value(value); err != nil {
+			d.errorf(n.line, "bad enum value for param %v: %v", p.ProperName, err)
+		}
+	}
+	return p
+}
+
+func (d *decoder) column(n *node) compiler.Column {
+	c := compiler.Column{Type: compiler.String}
+	for _, f := range d.pairs(n) {
+		switch f.key {
+		case "name":
+			c.ProperName = d.string(f.value)
+		case "type":
+			c.Type = d.typ(f.value)
+		case "blurb":
+			c.Blurb = d.string(f.value)
+		case "description":
+			c.Description = d.string(f.value)
+		case "sensitive":
+			c.Sensitive = d.bool(f.value)
+		default:
+			d.unknown(f)
+		}
+	}
+	if c.ProperName == "" {
+		d.errorf(n.line, "a column needs a name")
+	}
+	return c
+}
+
+// Apply merges the sidecar into the docs compiled from a file's
+// statements, returning a doc for each statement documented in either, in
+// the order of the statements. Anything the sidecar sets that the doc
+// comment already sets differently is a conflict, reported as Diagnostics.
+func (f *File) Apply(statements []tokenizer.Statement, docs []compiler.QueryDoc) ([]compiler.QueryDoc, error) {
+	m := merger{}
+	// Docs are matched to their statements by where they end.
+	inline := make(map[tokenizer.Position]int)
+	for i, doc := range docs {
+		inline[doc.End] = i
+	}
+	byName := make(map[string]int)
+	for i, s := range statements {
+		if j, ok := inline[s.End]; ok {
+			byName[docs[j].Name] = i
+		}
+	}
+	entries := make(map[int]Entry)
+	for _, e := range f.Statements {
+		i := e.Index - 1
+		if e.Index == 0 {
+			var ok bool
+			if i, ok = byName[e.Name]; !ok {
+				m.errorf(e.Pos.Line, "no query is named %v", e.Name)
+				continue
+			}
+		} else if i >= len(statements) {
+			m.errorf(e.Pos.Line, "there are only %d statements", len(statements))
+			continue
+		}
+		if _, ok := entries[i]; ok {
+			m.errorf(e.Pos.Line, "statement %d is documented twice", i+1)
+			continue
+		}
+		entries[i] = e
+	}
+	merged := make([]compiler.QueryDoc, 0, len(docs)+len(entries))
+	used := make(map[int]bool)
+	for i, s := range statements {
+		j, documented := inline[s.End]
+		e, ok := entries[i]
+		switch {
+		case documented && ok:
+			used[j] = true
+			merged = append(merged, m.merge(docs[j], e))
+		case documented:
+			used[j] = true
+			merged = append(merged, docs[j])
+		case ok:
+			q := compiler.NewQueryDocs()
+			q.SQL, q.Pos, q.End = s.SQL, s.Pos, s.End
+			merged = append(merged, m.merge(q, e))
+		}
+	}
+	for j, doc := range docs {
+		if !used[j] {
+			merged = append(merged, doc)
+		}
+	}
+	if len(m.diags) > 0 {
+		sort.SliceStable(m.diags, func(i, j int) bool { return m.diags[i].Pos.Line < m.diags[j].Pos.Line })
+		return merged, m.diags
+	}
+	return merged, nil
+}
+
+type merger struct {
+	decoder
+}
+
+// set sets a field the entry gives, unless the doc comment gave it
+// differently, reporting whether it did.
+func (m *merger) set(line int, what string, field *string, value string) bool {
+	if value == "" || *field == value {
+		return false
+	}
+	if *field != "" {
+		m.errorf(line, "%v conflicts: %q in the doc comment, %q in the sidecar", what, *field, value)
+		return false
+	}
+	*field = value
+	return true
+}
+
+func (m *merger) merge(q compiler.QueryDoc, e Entry) compiler.QueryDoc {
+	line := e.Pos.Line
+	// A name derived from the title wasn't given, so may be replaced.
+	if e.Name != "" && !q.Named {
+		q.Name = ""
+	}
+	if m.set(line, "name", &q.Name, e.Name) {
+		q.Named = true
+	}
+	m.set(line, "title", &q.Title, e.Title)
+	if m.set(line, "description", &q.Description, e.Description) {
+		q.Markup, q.DescriptionHTML = compiler.Describe(q.Description)
+	}
+	if q.Name == "" {
+		q.Name = compiler.Slug(q.Title)
+	}
+	params := make(map[string]bool)
+	for _, p := range q.Params {
+		params[p.ProperName] = true
+	}
+	for _, p := range e.Params {
+		if params[p.ProperName] {
+			m.errorf(e.lines["param "+p.ProperName], "param %v is declared in the doc comment and the sidecar", p.ProperName)
+			continue
+		}
+		p.Markup, p.DescriptionHTML = compiler.Describe(p.Description)
+		q.Params = append(q.Params, p)
+	}
+	if e.Table == nil {
+		return q
+	}
+	if q.Output.Columns == nil {
+		q.Output.Columns = make([]compiler.Column, 0)
+	}
+	m.set(line, "table title", &q.Output.Title, e.Table.Title)
+	if m.set(line, "table description", &q.Output.Description, e.Table.Description) {
+		q.Output.Markup, q.Output.DescriptionHTML = compiler.Describe(q.Output.Description)
+	}
+	columns := make(map[string]bool)
+	for _, c := range q.Output.Columns {
+		columns[c.ProperName] = true
+	}
+	for _, c := range e.Table.Columns {
+		if columns[c.ProperName] {
+			m.errorf(e.lines["column "+c.ProperName], "column %v is declared in the doc comment and the sidecar", c.ProperName)
+			continue
+		}
+		c.Markup, c.DescriptionHTML = compiler.Describe(c.Description)
+		q.Output.Columns = append(q.Output.Columns, c)
+	}
+	return q
+}
@@ -0,0 +1,162 @@
+package sidecar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+const sql = `SELECT id FROM users WHERE seen > ${since};
+/**
+@name orders
+@title "Orders"
+@param status "Status" "Which orders"
+*/
+SELECT * FROM orders WHERE status = ${status};
+SELECT 3;`
+
+const docs = `# Generated queries.
+statements:
+  - index: 1
+    name: active_users
+    title: 'Active users'
+    description: |
+      Users seen lately.
+
+      Not *all* of them.
+    params:
+    - name: since
+      type: date
+      blurb: Since # when
+      description: "Only users seen since this day."
+      default: "2020-01-01"
+    table:
+      columns:
+        - name: id
+          type: integer
+          blurb: ID
+          description: >-
+            The user's
+            ID.
+          sensitive: true
+  - name: orders
+    title: Orders
+    description: All orders with a status.
+`
+
+func compile(t *testing.T, src string) ([]tokenizer.Statement, []compiler.QueryDoc) {
+	statements, err := tokenizer.Statements(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := tokenizer.NewTokenizer(strings.NewReader(src))
+	tok.Tokenize()
+	compiled, err := compiler.Compile(tok.Tokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statements, compiled
+}
+
+func TestApply(t *testing.T) {
+	f, err := Parse(strings.NewReader(docs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := f.Apply(compile(t, sql))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 {
+		t.Fatalf("Wrong number of docs. Got %v want 2", len(merged))
+	}
+	users := merged[0]
+	if users.Name != "active_users" || users.Title != "Active users" || users.SQL != "SELECT id FROM users WHERE seen > ${since}" {
+		t.Errorf("Wrong sidecar doc. Got %+v", users)
+	}
+	if users.Description != "Users seen lately.\n\nNot *all* of them.\n" || users.Markup == nil {
+		t.Errorf("Wrong description. Got %q", users.Description)
+	}
+	p := users.Params[0]
+	if p.ProperName != "since" || p.Type != compiler.Date || p.Blurb != "Since" || *p.Default != "2020-01-01" {
+		t.Errorf("Wrong param. Got %+v", p)
+	}
+	c := users.Output.Columns[0]
+	if c.Type != compiler.Integer || c.Description != "The user's ID." || !c.Sensitive {
+		t.Errorf("Wrong column. Got %+v", c)
+	}
+	orders := merged[1]
+	if orders.Description != "All orders with a status." || len(orders.Params) != 1 {
+		t.Errorf("Sidecar wasn't merged into the doc comment. Got %+v", orders)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	f, err := Parse(strings.NewReader(`statements:
+  - name: orders
+    title: Other orders
+    params:
+      - name: status
+  - index: 2
+  - index: 9
+  - name: nobody
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Apply(compile(t, sql))
+	diags := compiler.AsDiagnostics(err)
+	want := []int{2, 5, 6, 7, 8}
+	if len(diags) != len(want) {
+		t.Fatalf("Wrong diagnostics. Got %v want lines %v", err, want)
+	}
+	for i, d := range diags {
+		if d.Pos.Line != want[i] {
+			t.Errorf("Wrong line for %v. Got %v want %v", d.Message, d.Pos.Line, want[i])
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	src := `/**
+@title "Active users"
+*/
+SELECT 1;
+/**
+@name active_users
+@title "Active users"
+*/
+SELECT 2;`
+	f, err := Parse(strings.NewReader("statements:\n  - index: 1\n    name: recent\n  - index: 2\n    name: latest\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := f.Apply(compile(t, src))
+	diags := compiler.AsDiagnostics(err)
+	if len(diags) != 1 || diags[0].Pos.Line != 4 {
+		t.Fatalf("Expected an explicit @name to conflict. Got %v", err)
+	}
+	if merged[0].Name != "recent" || !merged[0].Named {
+		t.Errorf("A name derived from the title wasn't replaced. Got %v", merged[0].Name)
+	}
+	if merged[1].Name != "active_users" {
+		t.Errorf("An explicit @name was replaced. Got %v", merged[1].Name)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	inputs := map[string]int{
+		"statements:\n  - index: one\n":             2,
+		"statements:\n  - index: 1\n    color: red": 3,
+		"statements:\n  - title: \"open\n":          2,
+		"statements:\n  - index: 1\n   title: x\n":  3,
+	}
+	for input, line := range inputs {
+		_, err := Parse(strings.NewReader(input))
+		if diags := compiler.AsDiagnostics(err); err == nil || diags[0].Pos.Line != line {
+			t.Errorf("Wrong error for %q. Got %v want one on line %v", input, err, line)
+		}
+	}
+}
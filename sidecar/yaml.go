@@ -0,0 +1,363 @@
+package sidecar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/tokenizer"
+)
+
+// The subset of YAML sidecars are written in: block mappings and
+// sequences, plain and quoted scalars on one line, flow sequences of
+// scalars, and | and > block scalars.
+
+type kind int
+
+const (
+	scalarNode kind = iota
+	mapNode
+	seqNode
+)
+
+type node struct {
+	kind  kind
+	line  int
+	value string
+	pairs []pair
+	items []*node
+}
+
+type pair struct {
+	key   string
+	line  int
+	value *node
+}
+
+func (n *node) pos() tokenizer.Position {
+	return tokenizer.Position{Line: n.line, Column: 1}
+}
+
+type parser struct {
+	lines []string
+	i     int
+}
+
+func errorAt(line int, format string, args ...interface{}) error {
+	return compiler.Diagnostic{Pos: tokenizer.Position{Line: line, Column: 1}, Severity: compiler.SeverityError, Message: fmt.Sprintf(format, args...)}
+}
+
+func parseYAML(src string) (*node, error) {
+	p := &parser{lines: strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")}
+	p.skip()
+	if p.i < len(p.lines) && strings.TrimSpace(p.lines[p.i]) == "---" {
+		p.i++
+	}
+	indent, _, ok := p.current()
+	if !ok {
+		return &node{kind: mapNode, line: 1}, nil
+	}
+	n, err := p.parseNode(indent)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, ok := p.current(); ok {
+		return nil, errorAt(p.i+1, "unexpected indentation")
+	}
+	return n, nil
+}
+
+// skip moves past blank and comment lines.
+func (p *parser) skip() {
+	for ; p.i < len(p.lines); p.i++ {
+		text := strings.TrimSpace(p.lines[p.i])
+		if text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+	}
+}
+
+// current returns the indentation and text, less any comment, of the next
+// line with content.
+func (p *parser) current() (int, string, bool) {
+	p.skip()
+	if p.i >= len(p.lines) {
+		return 0, "", false
+	}
+	line := p.lines[p.i]
+	text := strings.TrimLeft(line, " ")
+	return len(line) - len(text), stripComment(strings.TrimRight(text, " \t\r")), true
+}
+
+func stripComment(text string) string {
+	var quote rune
+	prev := ' '
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && strings.ContainsRune(" [,:", prev):
+			quote = r
+		case r == '#' && (prev == ' ' || prev == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+		prev = r
+	}
+	return text
+}
+
+func isItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *parser) parseNode(indent int) (*node, error) {
+	_, text, _ := p.current()
+	if strings.HasPrefix(text, "\t") {
+		return nil, errorAt(p.i+1, "indent with spaces, not tabs")
+	}
+	if isItem(text) {
+		return p.parseSeq(indent)
+	}
+	if _, _, ok := splitKey(text); ok {
+		return p.parseMap(indent)
+	}
+	line := p.i + 1
+	p.i++
+	return parseScalar(text, line)
+}
+
+func (p *parser) parseSeq(indent int) (*node, error) {
+	seq := &node{kind: seqNode, line: p.i + 1}
+	for {
+		at, text, ok := p.current()
+		if !ok || at != indent || !isItem(text) {
+			break
+		}
+		rest := strings.TrimLeft(text[1:], " ")
+		if rest == "" {
+			p.i++
+			item, err := p.parseNested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			seq.items = append(seq.items, item)
+			continue
+		}
+		// Parse the rest of the line as though it began a line of its own,
+		// indented as far as it is.
+		offset := indent + len(text) - len(rest)
+		p.lines[p.i] = strings.Repeat(" ", offset) + p.lines[p.i][offset:]
+		item, err := p.parseNode(offset)
+		if err != nil {
+			return nil, err
+		}
+		seq.items = append(seq.items, item)
+	}
+	return seq, nil
+}
+
+func (p *parser) parseMap(indent int) (*node, error) {
+	m := &node{kind: mapNode, line: p.i + 1}
+	seen := make(map[string]bool)
+	for {
+		at, text, ok := p.current()
+		if !ok || at < indent {
+			break
+		}
+		line := p.i + 1
+		if at > indent {
+			return nil, errorAt(line, "unexpected indentation")
+		}
+		if isItem(text) {
+			break
+		}
+		key, rest, ok := splitKey(text)
+		if !ok {
+			return nil, errorAt(line, "expected key: value")
+		}
+		if seen[key] {
+			return nil, errorAt(line, "duplicate key %v", key)
+		}
+		seen[key] = true
+		p.i++
+		var value *node
+		var err error
+		switch {
+		case rest == "":
+			value, err = p.parseNested(indent, true)
+		case rest[0] == '|' || rest[0] == '>':
+			value, err = p.parseBlock(indent, rest, line)
+		default:
+			value, err = parseScalar(rest, line)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.pairs = append(m.pairs, pair{key: key, line: line, value: value})
+	}
+	return m, nil
+}
+
+// parseNested parses the value on the lines below a key or dash, or an
+// empty scalar if there is none. Sequences may be indented as far as the
+// key they belong to.
+func (p *parser) parseNested(indent int, key bool) (*node, error) {
+	line := p.i
+	at, text, ok := p.current()
+	if ok && (at > indent || key && at == indent && isItem(text)) {
+		return p.parseNode(at)
+	}
+	return &node{kind: scalarNode, line: line}, nil
+}
+
+// parseBlock parses a | or > block scalar.
+func (p *parser) parseBlock(indent int, header string, line int) (*node, error) {
+	folded := header[0] == '>'
+	chomp := header[1:]
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return nil, errorAt(line, "unsupported block scalar header %v", header)
+	}
+	lines := make([]string, 0)
+	content := -1
+	for ; p.i < len(p.lines); p.i++ {
+		raw := strings.TrimRight(p.lines[p.i], " \t\r")
+		if raw == "" {
+			lines = append(lines, "")
+			continue
+		}
+		at := len(raw) - len(strings.TrimLeft(raw, " "))
+		if content < 0 {
+			content = at
+		}
+		if at <= indent || at < content {
+			break
+		}
+		lines = append(lines, raw[content:])
+	}
+	// Trailing blank lines belong to whatever follows unless kept.
+	kept := len(lines)
+	for kept > 0 && lines[kept-1] == "" {
+		kept--
+	}
+	trailing := len(lines) - kept
+	lines = lines[:kept]
+	var value string
+	if folded {
+		b := strings.Builder{}
+		for i, l := range lines {
+			if l == "" {
+				b.WriteString("\n")
+				continue
+			}
+			if i > 0 && lines[i-1] != "" {
+				b.WriteString(" ")
+			}
+			b.WriteString(l)
+		}
+		value = b.String()
+	} else {
+		value = strings.Join(lines, "\n")
+	}
+	switch chomp {
+	case "":
+		if len(lines) > 0 {
+			value += "\n"
+		}
+	case "+":
+		value += strings.Repeat("\n", trailing+1)
+	}
+	return &node{kind: scalarNode, line: line, value: value}, nil
+}
+
+// splitKey splits a "key: value" line.
+func splitKey(text string) (key, rest string, ok bool) {
+	if text == "" {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		k, err := parseScalar(text[:end+2], 0)
+		after := text[end+2:]
+		if err != nil || !(after == ":" || strings.HasPrefix(after, ": ")) {
+			return "", "", false
+		}
+		return k.value, strings.TrimSpace(after[1:]), true
+	}
+	if i := strings.Index(text, ": "); i > 0 {
+		return text[:i], strings.TrimSpace(text[i+2:]), true
+	}
+	if strings.HasSuffix(text, ":") && len(text) > 1 {
+		return text[:len(text)-1], "", true
+	}
+	return "", "", false
+}
+
+func parseScalar(text string, line int) (*node, error) {
+	n := &node{kind: scalarNode, line: line}
+	switch {
+	case text == "" || text == "~" || text == "null":
+	case text[0] == '"':
+		if len(text) < 2 || !strings.HasSuffix(text, `"`) {
+			return nil, errorAt(line, "strings must close on the line they open")
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, errorAt(line, "bad string %v", text)
+		}
+		n.value = s
+	case text[0] == '\'':
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, errorAt(line, "strings must close on the line they open")
+		}
+		n.value = strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	case text[0] == '[':
+		if !strings.HasSuffix(text, "]") {
+			return nil, errorAt(line, "sequences must close on the line they open")
+		}
+		n.kind = seqNode
+		for _, item := range splitFlow(text[1 : len(text)-1]) {
+			v, err := parseScalar(item, line)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, v)
+		}
+	case text == "{}":
+		n.kind = mapNode
+	case text[0] == '{':
+		return nil, errorAt(line, "flow mappings aren't supported")
+	default:
+		n.value = text
+	}
+	return n, nil
+}
+
+// splitFlow splits the items of a flow sequence on commas outside quotes.
+func splitFlow(s string) []string {
+	items := make([]string, 0)
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return items
+}
@@ -1,41 +1,122 @@
 package tokenizer
 
-/*
-Known bugs:
-	/*X where X is o * else causes a bad state since the reader isn't putting X  and * back
-*/
-
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"iter"
 	"strings"
+	"unicode"
 )
 
 type Tokenizer struct {
-	src    io.RuneScanner
+	src    *bufio.Reader
 	tokens []Tokener
+	// errors are the syntax errors recovered from, each also in tokens as
+	// an ErrorToken.
+	errors []*Error
+	ctx    context.Context
+	limits Limits
+	// reads counts runes read, docs docs opened and depth the brace
+	// blocks currently open, all checked against limits.
+	reads int
+	docs  int
+	depth int
+	// next is the index in tokens of the next token Next hands out, and
+	// err the error that ended tokenizing.
+	next int
+	err  error
+	// pos is the position of the next rune, last that of the rune most
+	// recently read.
+	pos  Position
+	last Position
+	prev Position
+}
+
+// Position is a location in the source. Line and Column are 1 based,
+// Column counting runes.
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Error is a tokenizing error at a position in the source. Err is the
+// cause, if any, such as a canceled context.
+type Error struct {
+	Pos Position
+	Msg string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrLimit is the cause of every Error for input past its Limits.
+var ErrLimit = errors.New("limit exceeded")
+
+// Limits bound the input a Tokenizer accepts, so a huge or hostile source
+// fails with an Error rather than exhausting memory. Zero fields are
+// unlimited.
+type Limits struct {
+	// MaxBytes bounds the whole source.
+	MaxBytes int
+	// MaxDocs bounds the number of doc comments.
+	MaxDocs int
+	// MaxTextLength bounds, in bytes, any one string, word or annotation
+	// name.
+	MaxTextLength int
+	// MaxNesting bounds how deeply { } blocks nest.
+	MaxNesting int
 }
 
 func (t *Tokenizer) Tokens() []Tokener {
 	return t.tokens
 }
 
+// Errors returns the syntax errors tokenizing recovered from.
+func (t *Tokenizer) Errors() []*Error {
+	return t.errors
+}
+
 type (
 	Tokener interface {
 		Original() string
 		SetOriginal(string)
+		Pos() Position
+	}
+
+	// ErrorToken stands in for malformed input that was skipped. Its
+	// original is the error message.
+	ErrorToken struct {
+		Token
+		Err *Error
 	}
 
 	Token struct {
 		original string
+		pos      Position
 	}
 
 	OpenDoc struct {
 		Token
 	}
 
+	// CloseDoc carries the SQL statement that followed the doc.
 	CloseDoc struct {
 		Token
+		Statement string
 	}
 
 	OpenBlock struct {
@@ -77,6 +158,90 @@ type (
 	BareWord struct {
 		Token
 	}
+
+	Required struct {
+		Token
+	}
+
+	Default struct {
+		Token
+	}
+
+	Enum struct {
+		Token
+	}
+
+	Name struct {
+		Token
+	}
+
+	See struct {
+		Token
+	}
+
+	Owner struct {
+		Token
+	}
+
+	Team struct {
+		Token
+	}
+
+	Tags struct {
+		Token
+	}
+
+	Since struct {
+		Token
+	}
+
+	Deprecated struct {
+		Token
+	}
+
+	Timeout struct {
+		Token
+	}
+
+	Cache struct {
+		Token
+	}
+
+	ReadOnly struct {
+		Token
+	}
+
+	MaxRows struct {
+		Token
+	}
+
+	Requires struct {
+		Token
+	}
+
+	Sensitive struct {
+		Token
+	}
+
+	Example struct {
+		Token
+	}
+
+	Value struct {
+		Token
+	}
+
+	Expect struct {
+		Token
+	}
+
+	Define struct {
+		Token
+	}
+
+	Include struct {
+		Token
+	}
 )
 
 func (t Token) Original() string {
@@ -87,44 +252,216 @@ func (t Token) SetOriginal(s string) {
 	t.original = s
 }
 
+func (t Token) Pos() Position {
+	return t.pos
+}
+
 func NewTokenizer(src io.Reader) *Tokenizer {
-	return &Tokenizer{src: bufio.NewReader(src), tokens: make([]Tokener, 0)}
+	return NewTokenizerContext(context.Background(), src, Limits{})
+}
+
+// NewTokenizerContext returns a tokenizer that stops once ctx is done or
+// src goes past limits.
+func NewTokenizerContext(ctx context.Context, src io.Reader, limits Limits) *Tokenizer {
+	start := Position{Line: 1, Column: 1}
+	return &Tokenizer{src: bufio.NewReader(src), tokens: make([]Tokener, 0), ctx: ctx, limits: limits, pos: start, last: start}
 }
 
 func (t *Tokenizer) Peek() (s string, err error) {
 	s, err = t.Read()
-	t.src.UnreadRune()
+	if err == nil {
+		t.src.UnreadRune()
+		t.pos, t.last = t.last, t.prev
+	}
 	return
 }
 
 func (t *Tokenizer) Read() (s string, err error) {
-	r, _, err := t.src.ReadRune()
+	r, size, err := t.src.ReadRune()
+	if err != nil {
+		return "", err
+	}
 	s = string(r)
+	t.prev, t.last = t.last, t.pos
+	t.pos.Offset += size
+	if t.limits.MaxBytes > 0 && t.pos.Offset > t.limits.MaxBytes {
+		return "", t.limitf(t.last, "input is larger than %d bytes", t.limits.MaxBytes)
+	}
+	// Checking the context is cheap but not free.
+	if t.reads++; t.reads%1024 == 0 {
+		if err := t.ctx.Err(); err != nil {
+			return "", &Error{Pos: t.last, Msg: "tokenizing stopped: " + err.Error(), Err: err}
+		}
+	}
+	if r == '\n' {
+		t.pos.Line++
+		t.pos.Column = 1
+	} else {
+		t.pos.Column++
+	}
 	return
 }
 
+func (t *Tokenizer) token(s string, pos Position) Token {
+	return Token{original: s, pos: pos}
+}
+
+// checkLength fails once text that began at start outgrows the limit.
+func (t *Tokenizer) checkLength(start Position, b *strings.Builder) error {
+	if t.limits.MaxTextLength > 0 && b.Len() > t.limits.MaxTextLength {
+		return t.limitf(start, "text is longer than %d bytes", t.limits.MaxTextLength)
+	}
+	return nil
+}
+
+func (t *Tokenizer) errorf(pos Position, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (t *Tokenizer) limitf(pos Position, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...), Err: ErrLimit}
+}
+
+// recoverFrom records a syntax error as an ErrorToken and skips ahead to
+// the next @, }, */ or ; where tokenizing can pick up again. Other errors,
+// such as limits and I/O, are returned as is.
+func (t *Tokenizer) recoverFrom(err error) error {
+	var e *Error
+	if !errors.As(err, &e) || e.Err != nil {
+		return err
+	}
+	t.tokens = append(t.tokens, ErrorToken{t.token(e.Msg, e.Pos), e})
+	t.errors = append(t.errors, e)
+	for !t.closing() {
+		c, err := t.Peek()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch c {
+		case "@", "}", ";":
+			return nil
+		}
+		t.Read()
+	}
+	return nil
+}
+
+// annotationNames are the annotations tokenizeAnnotation knows.
+var annotationNames = map[string]bool{
+	"title": true, "description": true, "param": true, "column": true, "table": true,
+	"name": true, "see": true, "owner": true, "team": true, "tags": true, "since": true,
+	"deprecated": true, "example": true, "value": true, "expect": true, "timeout": true,
+	"cache": true, "maxrows": true, "readonly": true, "requires": true, "sensitive": true,
+	"required": true, "default": true, "enum": true, "define": true, "include": true,
+}
+
+// annotationAhead reports whether the line ahead starts with a known
+// annotation.
+func (t *Tokenizer) annotationAhead() bool {
+	ahead, _ := t.src.Peek(64)
+	line := strings.TrimLeft(string(ahead), " \t")
+	if !strings.HasPrefix(line, "@") {
+		return false
+	}
+	end := strings.IndexAny(line, " \t\r\n")
+	if end < 0 {
+		return false
+	}
+	return annotationNames[line[1:end]]
+}
+
+// closing reports whether a comment is about to close.
+func (t *Tokenizer) closing() bool {
+	b, _ := t.src.Peek(2)
+	return string(b) == "*/"
+}
+
+// skipComment consumes the rest of a plain comment, returning it.
+func (t *Tokenizer) skipComment() (string, error) {
+	b := strings.Builder{}
+	for !t.closing() {
+		c, err := t.Read()
+		if err == io.EOF {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(c)
+	}
+	t.Read()
+	t.Read()
+	b.WriteString("*/")
+	return b.String(), nil
+}
+
 func (t *Tokenizer) Tokenize() error {
 	for {
-		c, err := t.Read()
-		switch err {
-		case nil:
-			break
-		case io.EOF:
+		err := t.step()
+		if err == io.EOF {
 			return nil
-		default:
+		}
+		if err != nil {
 			return err
 		}
-		switch c {
-		case "/":
-			err := t.attemptDoc()
-			if err != nil {
-				return err
+	}
+}
+
+// Next returns the next token, tokenizing only as far into the source as
+// it needs to, so at most one doc's tokens are held at a time. It returns
+// io.EOF at the end of the source. Next and Tokenize shouldn't be mixed.
+func (t *Tokenizer) Next() (Tokener, error) {
+	for t.next >= len(t.tokens) {
+		if t.err != nil {
+			return nil, t.err
+		}
+		// Everything buffered has been handed out.
+		t.tokens, t.next = t.tokens[:0], 0
+		t.err = t.step()
+	}
+	tok := t.tokens[t.next]
+	t.next++
+	return tok, nil
+}
+
+// All yields the tokens Next returns, ending with the first error other
+// than io.EOF.
+func (t *Tokenizer) All() iter.Seq2[Tokener, error] {
+	return func(yield func(Tokener, error) bool) {
+		for {
+			tok, err := t.Next()
+			if err == io.EOF || !yield(tok, err) || err != nil {
+				return
 			}
 		}
 	}
 }
 
-func (t *Tokenizer) attemptDoc() error {
+// step tokenizes up to the end of the next doc comment.
+func (t *Tokenizer) step() error {
+	for {
+		c, err := t.Read()
+		if err != nil {
+			return err
+		}
+		if c != "/" {
+			continue
+		}
+		n := len(t.tokens)
+		err = t.attemptDoc(t.last)
+		if err != nil {
+			return err
+		}
+		if len(t.tokens) > n {
+			return nil
+		}
+	}
+}
+
+func (t *Tokenizer) attemptDoc(start Position) error {
 	peek, err := t.Peek()
 	switch err {
 	case nil:
@@ -149,54 +486,82 @@ func (t *Tokenizer) attemptDoc() error {
 		switch peek {
 		case "*":
 			t.Read()
-			t.tokens = append(t.tokens, OpenDoc{Token{"/**"}})
-			t.tokens = append(t.tokens, OpenBlock{Token{"/**"}})
+			if peek, _ := t.Peek(); peek == "/" {
+				// /**/ is an empty comment, not a doc.
+				t.Read()
+				return nil
+			}
+			if t.docs++; t.limits.MaxDocs > 0 && t.docs > t.limits.MaxDocs {
+				return t.limitf(start, "more than %d docs", t.limits.MaxDocs)
+			}
+			t.tokens = append(t.tokens, OpenDoc{t.token("/**", start)})
+			t.tokens = append(t.tokens, OpenBlock{t.token("/**", start)})
 			return t.tokenizeDoc()
 		}
+		_, err = t.skipComment()
+		return err
 	}
 	return nil
 }
 
 func (t *Tokenizer) tokenizeDoc() error {
+	n := len(t.tokens)
 	err := t.tokenizeBlock()
 	if err != nil {
 		return err
 	}
+	// A @define block documents no statement, so it is a doc of its own.
+	for _, tok := range t.tokens[n:] {
+		if _, ok := tok.(Define); ok {
+			t.tokens = append(t.tokens, CloseDoc{t.token("*/", t.last), ""})
+			return nil
+		}
+	}
+	statement := strings.Builder{}
 	for {
 		c, err := t.Read()
 		switch err {
 		case nil:
 			break
 		case io.EOF:
-			t.tokens = append(t.tokens, CloseDoc{Token{"EOF"}})
+			t.tokens = append(t.tokens, CloseDoc{t.token("EOF", t.pos), strings.TrimSpace(statement.String())})
 			return io.EOF
 		default:
 			return err
 		}
 		switch c {
 		case ";":
-			t.tokens = append(t.tokens, CloseDoc{Token{";"}})
+			t.tokens = append(t.tokens, CloseDoc{t.token(";", t.last), strings.TrimSpace(statement.String())})
 			return nil
 		case "/":
-			err := t.attemptBlock()
+			skipped, err := t.attemptBlock(t.last)
 			if err != nil {
 				return err
 			}
-
+			statement.WriteString(skipped)
+		case "'":
+			// Semicolons inside string literals don't end the statement.
+			statement.WriteString(c)
+			err := t.consumeLiteral(&statement)
+			if err != nil {
+				return err
+			}
+		default:
+			statement.WriteString(c)
 		}
-
 	}
 }
 
-func (t *Tokenizer) attemptBlock() error {
+// attemptBlock returns what it consumed if it wasn't the start of a block.
+func (t *Tokenizer) attemptBlock(start Position) (string, error) {
 	peek, err := t.Peek()
 	switch err {
 	case nil:
 		break
 	case io.EOF:
-		return nil
+		return "/", nil
 	default:
-		return err
+		return "", err
 	}
 	switch peek {
 	case "*":
@@ -206,18 +571,43 @@ func (t *Tokenizer) attemptBlock() error {
 		case nil:
 			break
 		case io.EOF:
-			return nil
+			return "/*", nil
 		default:
-			return err
+			return "", err
 		}
 		switch peek {
 		case "*":
 			t.Read()
-			t.tokens = append(t.tokens, OpenBlock{Token{"/**"}})
-			return t.tokenizeBlock()
+			if peek, _ := t.Peek(); peek == "/" {
+				t.Read()
+				return "/**/", nil
+			}
+			t.tokens = append(t.tokens, OpenBlock{t.token("/**", start)})
+			return "", t.tokenizeBlock()
+		}
+		comment, err := t.skipComment()
+		return "/*" + comment, err
+	}
+	return "/", nil
+}
+
+func (t *Tokenizer) consumeLiteral(b *strings.Builder) error {
+	for {
+		c, err := t.Read()
+		switch err {
+		case nil:
+			break
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+		b.WriteString(c)
+		switch c {
+		case "'":
+			return nil
 		}
 	}
-	return nil
 }
 
 func (t *Tokenizer) tokenizeBlock() error {
@@ -244,12 +634,13 @@ func (t *Tokenizer) tokenizeBlock() error {
 			}
 			switch peek {
 			case "/":
+				start := t.last
 				t.Read()
-				t.tokens = append(t.tokens, CloseBlock{Token{"*/"}})
+				t.tokens = append(t.tokens, CloseBlock{t.token("*/", start)})
 				return nil
 			}
 		case "@":
-			err := t.tokenizeAnnotation()
+			err := t.recoverFrom(t.tokenizeAnnotation(t.last))
 			if err != nil {
 				return err
 			}
@@ -257,7 +648,7 @@ func (t *Tokenizer) tokenizeBlock() error {
 	}
 }
 
-func (t *Tokenizer) tokenizeAnnotation() error {
+func (t *Tokenizer) tokenizeAnnotation(start Position) error {
 	annotation, err := t.buildAnnotationName()
 	if err != nil {
 		return err
@@ -265,33 +656,172 @@ func (t *Tokenizer) tokenizeAnnotation() error {
 	switch annotation {
 	// @TODO magic strings. evil.
 	case "title":
-		t.tokens = append(t.tokens, Title{Token{annotation}})
+		t.tokens = append(t.tokens, Title{t.token(annotation, start)})
 		err := t.consumeUntilQuote()
 		if err != nil {
 			return err
 		}
 		return t.tokenizeText()
 	case "description":
-		t.tokens = append(t.tokens, Desc{Token{annotation}})
+		t.tokens = append(t.tokens, Desc{t.token(annotation, start)})
 		err := t.consumeUntilQuote()
 		if err != nil {
 			return err
 		}
 		return t.tokenizeText()
 	case "param":
-		t.tokens = append(t.tokens, Param{Token{annotation}})
-		t.tokenizeParamColumnContents()
+		t.tokens = append(t.tokens, Param{t.token(annotation, start)})
+		return t.tokenizeParamColumnContents()
 	case "column":
-		t.tokens = append(t.tokens, Column{Token{annotation}})
-		t.tokenizeParamColumnContents()
+		t.tokens = append(t.tokens, Column{t.token(annotation, start)})
+		return t.tokenizeParamColumnContents()
 	case "table":
-		t.tokens = append(t.tokens, Table{Token{annotation}})
-		t.tokenizeTable()
+		t.tokens = append(t.tokens, Table{t.token(annotation, start)})
+		return t.tokenizeTable()
+	case "name":
+		t.tokens = append(t.tokens, Name{t.token(annotation, start)})
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeBareWord()
+	case "see":
+		t.tokens = append(t.tokens, See{t.token(annotation, start)})
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeBareWord()
+	case "owner", "team", "since":
+		switch annotation {
+		case "owner":
+			t.tokens = append(t.tokens, Owner{t.token(annotation, start)})
+		case "team":
+			t.tokens = append(t.tokens, Team{t.token(annotation, start)})
+		default:
+			t.tokens = append(t.tokens, Since{t.token(annotation, start)})
+		}
+		err := t.consumeUntilQuote()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeText()
+	case "tags":
+		t.tokens = append(t.tokens, Tags{t.token(annotation, start)})
+		return t.tokenizeTextList()
+	case "deprecated":
+		// A reason and optionally the name of the query replacing this one.
+		t.tokens = append(t.tokens, Deprecated{t.token(annotation, start)})
+		err := t.consumeUntilQuote()
+		if err != nil {
+			return err
+		}
+		err = t.tokenizeText()
+		if err != nil {
+			return err
+		}
+		err = t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		peek, err := t.Peek()
+		if err != nil || !isWordStart(peek) {
+			return nil
+		}
+		return t.tokenizeBareWord()
+	case "example":
+		t.tokens = append(t.tokens, Example{t.token(annotation, start)})
+		err := t.tokenizeOptionalText()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeTable()
+	case "value":
+		t.tokens = append(t.tokens, Value{t.token(annotation, start)})
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		err = t.tokenizeBareWord()
+		if err != nil {
+			return err
+		}
+		err = t.consumeUntilQuote()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeText()
+	case "expect":
+		// Either inline CSV or file "path.csv".
+		t.tokens = append(t.tokens, Expect{t.token(annotation, start)})
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		peek, err := t.Peek()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == nil && peek != "\"" {
+			err = t.tokenizeBareWord()
+			if err != nil {
+				return err
+			}
+		}
+		err = t.consumeUntilQuote()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeText()
+	case "timeout", "cache", "maxrows":
+		switch annotation {
+		case "timeout":
+			t.tokens = append(t.tokens, Timeout{t.token(annotation, start)})
+		case "cache":
+			t.tokens = append(t.tokens, Cache{t.token(annotation, start)})
+		default:
+			t.tokens = append(t.tokens, MaxRows{t.token(annotation, start)})
+		}
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeBareWord()
+	case "requires":
+		t.tokens = append(t.tokens, Requires{t.token(annotation, start)})
+		return t.tokenizeWordList()
+	case "define":
+		t.tokens = append(t.tokens, Define{t.token(annotation, start)})
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeBareWord()
+	case "include":
+		t.tokens = append(t.tokens, Include{t.token(annotation, start)})
+		return t.tokenizeWordList()
+	case "sensitive":
+		t.tokens = append(t.tokens, Sensitive{t.token(annotation, start)})
+	case "readonly":
+		t.tokens = append(t.tokens, ReadOnly{t.token(annotation, start)})
+	case "required":
+		t.tokens = append(t.tokens, Required{t.token(annotation, start)})
+	case "default":
+		t.tokens = append(t.tokens, Default{t.token(annotation, start)})
+		err := t.consumeUntilQuote()
+		if err != nil {
+			return err
+		}
+		return t.tokenizeText()
+	case "enum":
+		t.tokens = append(t.tokens, Enum{t.token(annotation, start)})
+		return t.tokenizeTextList()
 	}
 	return nil
 }
 
 func (t *Tokenizer) buildAnnotationName() (string, error) {
+	start := t.pos
 	b := strings.Builder{}
 	for {
 		c, err := t.Read()
@@ -308,9 +838,11 @@ func (t *Tokenizer) buildAnnotationName() (string, error) {
 			return b.String(), nil
 		default:
 			b.WriteString(c)
+			if err := t.checkLength(start, &b); err != nil {
+				return "", err
+			}
 		}
 	}
-	return "", nil
 }
 
 func (t *Tokenizer) tokenizeTable() error {
@@ -318,7 +850,17 @@ func (t *Tokenizer) tokenizeTable() error {
 	if err != nil {
 		return err
 	}
+	if t.depth++; t.limits.MaxNesting > 0 && t.depth > t.limits.MaxNesting {
+		return t.limitf(t.last, "blocks nest deeper than %d", t.limits.MaxNesting)
+	}
+	defer func() {
+		t.depth--
+	}()
+	open := t.last
 	for {
+		if t.closing() {
+			return t.errorf(open, "unterminated {")
+		}
 		c, err := t.Read()
 		switch err {
 		case nil:
@@ -332,7 +874,7 @@ func (t *Tokenizer) tokenizeTable() error {
 		case "}":
 			return nil
 		case "@":
-			err := t.tokenizeAnnotation()
+			err := t.recoverFrom(t.tokenizeAnnotation(t.last))
 			if err != nil {
 				return err
 			}
@@ -341,31 +883,102 @@ func (t *Tokenizer) tokenizeTable() error {
 }
 
 func (t *Tokenizer) tokenizeText() error {
+	// Text starts at its opening quote.
+	start := t.last
 	b := strings.Builder{}
 	for {
+		// A doc can't contain */, so a string running into one was never
+		// closed.
+		if t.closing() {
+			return t.errorf(start, "unterminated string")
+		}
 		c, err := t.Read()
 		switch err {
 		case nil:
 			break
 		case io.EOF:
-			return nil
+			return t.errorf(start, "unterminated string")
 		default:
 			return err
 		}
 		switch c {
 		case "\"":
-			t.tokens = append(t.tokens, Text{Token{b.String()}})
+			t.tokens = append(t.tokens, Text{t.token(b.String(), start)})
 			return nil
+		case "\n":
+			// Nor was one running into a line that starts an annotation.
+			if t.annotationAhead() {
+				return t.errorf(start, "unterminated string")
+			}
+			b.WriteString(c)
 		default:
 			b.WriteString(c)
+			if err := t.checkLength(start, &b); err != nil {
+				return err
+			}
 		}
 	}
 }
 
 func (t *Tokenizer) tokenizeBareWord() error {
+	start := t.pos
 	b := strings.Builder{}
+	for !t.closing() {
+		c, err := t.Peek()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if c == " " || c == "\n" || c == "\t" || c == "\r" {
+			t.Read()
+			break
+		}
+		if (c == "@" || c == "}") && b.Len() == 0 {
+			break
+		}
+		t.Read()
+		b.WriteString(c)
+		if err := t.checkLength(start, &b); err != nil {
+			return err
+		}
+	}
+	if b.Len() == 0 {
+		return t.errorf(start, "expected a word")
+	}
+	t.tokens = append(t.tokens, BareWord{t.token(b.String(), start)})
+	return nil
+}
+
+func isWordStart(c string) bool {
+	r := []rune(c)[0]
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// tokenizeOptionalText tokenizes a quoted string if one comes next.
+func (t *Tokenizer) tokenizeOptionalText() error {
+	err := t.consumeSpaces()
+	if err != nil {
+		return err
+	}
+	peek, err := t.Peek()
+	if err != nil || peek != "\"" {
+		return nil
+	}
+	t.Read()
+	return t.tokenizeText()
+}
+
+// tokenizeTextList tokenizes quoted strings until it finds something
+// that isn't one.
+func (t *Tokenizer) tokenizeTextList() error {
 	for {
-		c, err := t.Read()
+		err := t.consumeSpaces()
+		if err != nil {
+			return err
+		}
+		peek, err := t.Peek()
 		switch err {
 		case nil:
 			break
@@ -374,13 +987,55 @@ func (t *Tokenizer) tokenizeBareWord() error {
 		default:
 			return err
 		}
+		if peek != "\"" {
+			return nil
+		}
+		t.Read()
+		err = t.tokenizeText()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tokenizeWordList tokenizes the rest of the line as barewords separated
+// by commas or spaces.
+func (t *Tokenizer) tokenizeWordList() error {
+	b := strings.Builder{}
+	var start Position
+	flush := func() {
+		if b.Len() > 0 {
+			t.tokens = append(t.tokens, BareWord{t.token(b.String(), start)})
+			b.Reset()
+		}
+	}
+	for {
+		c, err := t.Peek()
+		switch err {
+		case nil:
+			break
+		case io.EOF:
+			flush()
+			return nil
+		default:
+			return err
+		}
 		switch c {
-		case " ", "\n", "\t", "\r":
-			t.tokens = append(t.tokens, BareWord{Token{b.String()}})
+		case "\n", "@", "*", "}":
+			flush()
 			return nil
+		case " ", "\t", "\r", ",":
+			flush()
 		default:
+			if b.Len() == 0 {
+				start = t.pos
+			}
 			b.WriteString(c)
+			if err := t.checkLength(start, &b); err != nil {
+				return err
+			}
 		}
+		t.Read()
 	}
 }
 
@@ -393,6 +1048,26 @@ func (t *Tokenizer) tokenizeParamColumnContents() error {
 	if err != nil {
 		return err
 	}
+	// A use:name reference is all there is; the rest comes from its
+	// definition.
+	if strings.HasPrefix(t.tokens[len(t.tokens)-1].Original(), "use:") {
+		return nil
+	}
+	// An optional type may sit between the name and the blurb.
+	err = t.consumeSpaces()
+	if err != nil {
+		return err
+	}
+	peek, err := t.Peek()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if err == nil && peek != "\"" {
+		err = t.tokenizeBareWord()
+		if err != nil {
+			return err
+		}
+	}
 	err = t.consumeUntilQuote()
 	if err != nil {
 		return err
@@ -414,6 +1089,9 @@ func (t *Tokenizer) tokenizeParamColumnContents() error {
 
 func (t *Tokenizer) consumeUntilQuote() error {
 	for {
+		if t.stopsValue() {
+			return t.errorf(t.pos, "expected a quoted string")
+		}
 		c, err := t.Read()
 		switch err {
 		case nil:
@@ -432,6 +1110,9 @@ func (t *Tokenizer) consumeUntilQuote() error {
 
 func (t *Tokenizer) consumeUntilLBracket() error {
 	for {
+		if t.stopsValue() {
+			return t.errorf(t.pos, "expected {")
+		}
 		c, err := t.Read()
 		switch err {
 		case nil:
@@ -448,6 +1129,16 @@ func (t *Tokenizer) consumeUntilLBracket() error {
 	}
 }
 
+// stopsValue reports whether the next annotation, or the end of a block
+// or comment, comes before the value being looked for.
+func (t *Tokenizer) stopsValue() bool {
+	if t.closing() {
+		return true
+	}
+	c, err := t.Peek()
+	return err == nil && (c == "@" || c == "}")
+}
+
 func (t *Tokenizer) consumeSpaces() error {
 	for {
 		c, err := t.Peek()
@@ -467,3 +1158,79 @@ func (t *Tokenizer) consumeSpaces() error {
 		}
 	}
 }
+
+// Statement is a SQL statement, documented or not. End is where its ;
+// is, or the end of the source, the same as the CloseDoc of its doc.
+type Statement struct {
+	SQL string
+	Pos Position
+	End Position
+}
+
+// Statements splits src into its statements, leaving out doc comments and
+// statements that are empty.
+func Statements(src io.Reader) ([]Statement, error) {
+	t := NewTokenizer(src)
+	statements := make([]Statement, 0)
+	b := strings.Builder{}
+	var pos Position
+	write := func(s string, at Position) {
+		if strings.TrimSpace(b.String()) == "" && strings.TrimSpace(s) != "" {
+			pos = at
+		}
+		b.WriteString(s)
+	}
+	end := func(at Position) {
+		if sql := strings.TrimSpace(b.String()); sql != "" {
+			statements = append(statements, Statement{SQL: sql, Pos: pos, End: at})
+		}
+		b.Reset()
+	}
+	for {
+		c, err := t.Read()
+		if err == io.EOF {
+			end(t.pos)
+			return statements, nil
+		}
+		if err != nil {
+			return statements, err
+		}
+		start := t.last
+		switch c {
+		case ";":
+			end(start)
+		case "'":
+			literal := strings.Builder{}
+			literal.WriteString(c)
+			if err := t.consumeLiteral(&literal); err != nil {
+				return statements, err
+			}
+			write(literal.String(), start)
+		case "/":
+			if peek, _ := t.Peek(); peek != "*" {
+				write(c, start)
+				continue
+			}
+			t.Read()
+			doc := false
+			if peek, _ := t.Peek(); peek == "*" {
+				t.Read()
+				if peek, _ := t.Peek(); peek == "/" {
+					t.Read()
+					write("/**/", start)
+					continue
+				}
+				doc = true
+			}
+			comment, err := t.skipComment()
+			if err != nil {
+				return statements, err
+			}
+			if !doc {
+				write("/*"+comment, start)
+			}
+		default:
+			write(c, start)
+		}
+	}
+}
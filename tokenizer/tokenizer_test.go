@@ -1,6 +1,9 @@
 package tokenizer
 
 import (
+	"context"
+	"errors"
+	"io"
 	"strings"
 	"testing"
 )
@@ -394,3 +397,306 @@ func TestWholeDocWithTitle(t *testing.T) {
 		}
 	}
 }
+
+const typedParam = `/**
+@param status text "Status" "Order status"
+@required
+@enum "open" "closed"
+@default "open"
+*/`
+
+func TestTypedParamWithModifiers(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(typedParam))
+	tok.Tokenize()
+	want := []string{"/**", "/**", "param", "status", "text", "Status", "Order status",
+		"required", "enum", "open", "closed", "default", "open", "*/", "EOF"}
+	if len(tok.tokens) != len(want) {
+		t.Fatalf("Document has incorrect number of tokens. Got %v want %v", len(tok.tokens), len(want))
+	}
+	for i, tok := range tok.tokens {
+		if tok.Original() != want[i] {
+			t.Errorf("Wrong token at index %v. Got '%v' want '%v'", i, tok.Original(), want[i])
+		}
+	}
+	switch typ := tok.tokens[4].(type) {
+	case BareWord:
+	default:
+		t.Errorf("Got wrong token type at index %v. Got %v want %v", 4, typ, "BareWord")
+	}
+	switch typ := tok.tokens[8].(type) {
+	case Enum:
+	default:
+		t.Errorf("Got wrong token type at index %v. Got %v want %v", 8, typ, "Enum")
+	}
+}
+
+const exampleDoc = `/**
+@example "open orders" {
+	@value status "open"
+	@expect "id
+		1"
+}
+@example {
+	@expect file "closed.csv"
+}
+*/`
+
+func TestExample(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(exampleDoc))
+	tok.Tokenize()
+	want := []string{"/**", "/**", "example", "open orders", "value", "status", "open", "expect", "id\n\t\t1",
+		"example", "expect", "file", "closed.csv", "*/", "EOF"}
+	if len(tok.tokens) != len(want) {
+		t.Fatalf("Document has incorrect number of tokens. Got %v want %v", len(tok.tokens), len(want))
+	}
+	for i, tok := range tok.tokens {
+		if tok.Original() != want[i] {
+			t.Errorf("Wrong token at index %v. Got '%v' want '%v'", i, tok.Original(), want[i])
+		}
+	}
+	switch typ := tok.tokens[11].(type) {
+	case BareWord:
+	default:
+		t.Errorf("Got wrong token type at index %v. Got %v want %v", 11, typ, "BareWord")
+	}
+}
+
+const governanceDoc = `/**
+@owner "ana@example.com"
+@team "billing"
+@tags "finance" "daily"
+@since "1.4"
+@deprecated "Scans every order." orders_v2
+*/`
+
+func TestGovernance(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(governanceDoc))
+	tok.Tokenize()
+	want := []string{"/**", "/**", "owner", "ana@example.com", "team", "billing", "tags", "finance", "daily",
+		"since", "1.4", "deprecated", "Scans every order.", "orders_v2", "*/", "EOF"}
+	if len(tok.tokens) != len(want) {
+		t.Fatalf("Document has incorrect number of tokens. Got %v want %v", len(tok.tokens), len(want))
+	}
+	for i, tok := range tok.tokens {
+		if tok.Original() != want[i] {
+			t.Errorf("Wrong token at index %v. Got '%v' want '%v'", i, tok.Original(), want[i])
+		}
+	}
+	switch typ := tok.tokens[13].(type) {
+	case BareWord:
+	default:
+		t.Errorf("Got wrong token type at index %v. Got %v want %v", 13, typ, "BareWord")
+	}
+}
+
+const requiresDoc = `/**
+@requires support, admin
+@table {
+	@column email "Email" "Where to reach them"
+	@sensitive
+} */`
+
+func TestRequires(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(requiresDoc))
+	tok.Tokenize()
+	want := []string{"/**", "/**", "requires", "support", "admin", "table",
+		"column", "email", "Email", "Where to reach them", "sensitive", "*/", "EOF"}
+	if len(tok.tokens) != len(want) {
+		t.Fatalf("Document has incorrect number of tokens. Got %v want %v", len(tok.tokens), len(want))
+	}
+	for i, tok := range tok.tokens {
+		if tok.Original() != want[i] {
+			t.Errorf("Wrong token at index %v. Got '%v' want '%v'", i, tok.Original(), want[i])
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	src := wholeDocTestSemi + governanceDoc + "\n" + statementDoc
+	want := NewTokenizer(strings.NewReader(src))
+	want.Tokenize()
+	tok := NewTokenizer(strings.NewReader(src))
+	got := make([]Tokener, 0)
+	for token, err := range tok.All() {
+		if err != nil {
+			t.Fatalf("Unexpected err %v", err)
+		}
+		got = append(got, token)
+	}
+	if len(got) != len(want.tokens) {
+		t.Fatalf("Wrong number of tokens. Got %v want %v", len(got), len(want.tokens))
+	}
+	for i := range got {
+		if got[i].Original() != want.tokens[i].Original() || got[i].Pos() != want.tokens[i].Pos() {
+			t.Errorf("Wrong token at index %v. Got '%v' want '%v'", i, got[i].Original(), want.tokens[i].Original())
+		}
+	}
+	if _, err := tok.Next(); err != io.EOF {
+		t.Errorf("Wrong error after the last token. Got %v want %v", err, io.EOF)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		src    string
+		limits Limits
+		want   string
+	}{
+		{wholeDocTestSemi, Limits{MaxBytes: 20}, "3:8: input is larger than 20 bytes"},
+		{wholeDocTestSemi + wholeDocTestSemi, Limits{MaxDocs: 1}, "19:1: more than 1 docs"},
+		{governanceDoc, Limits{MaxTextLength: 10}, "2:8: text is longer than 10 bytes"},
+		{"/**\n@table { @table { @table {} } }\n*/", Limits{MaxNesting: 2}, "2:26: blocks nest deeper than 2"},
+	}
+	for _, c := range cases {
+		err := NewTokenizerContext(context.Background(), strings.NewReader(c.src), c.limits).Tokenize()
+		if err == nil || err.Error() != c.want || !errors.Is(err, ErrLimit) {
+			t.Errorf("Wrong error for %+v. Got %v want %v", c.limits, err, c.want)
+		}
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	src := strings.Repeat(wholeDocTestSemi, 100)
+	err := NewTokenizerContext(ctx, strings.NewReader(src), Limits{}).Tokenize()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Wrong error. Got %v want %v", err, context.Canceled)
+	}
+}
+
+const statementDoc = `/**
+@title "Statement"
+*/
+SELECT 'a;b' /* not a doc */ FROM t;`
+
+func TestCloseDocStatement(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(statementDoc))
+	tok.Tokenize()
+	last := tok.tokens[len(tok.tokens)-1]
+	switch typ := last.(type) {
+	case CloseDoc:
+		want := "SELECT 'a;b' /* not a doc */ FROM t"
+		if typ.Statement != want {
+			t.Errorf("Incorrect statement. Got '%v' want '%v'", typ.Statement, want)
+		}
+	default:
+		t.Errorf("Got wrong token type at end. Got %v want %v", typ, "CloseDoc")
+	}
+}
+
+const positionDoc = `SELECT 0;
+/**
+  @title "Where"
+*/`
+
+func TestPositions(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(positionDoc))
+	tok.Tokenize()
+	want := []Position{
+		{Offset: 10, Line: 2, Column: 1},
+		{Offset: 10, Line: 2, Column: 1},
+		{Offset: 16, Line: 3, Column: 3},
+		{Offset: 23, Line: 3, Column: 10},
+		{Offset: 31, Line: 4, Column: 1},
+	}
+	for i, p := range want {
+		if tok.tokens[i].Pos() != p {
+			t.Errorf("Wrong position for token %v. Got %v want %v", i, tok.tokens[i].Pos(), p)
+		}
+	}
+}
+
+func TestUnterminatedText(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader("/**\n@title \"never closed\n*/"))
+	err := tok.Tokenize()
+	if err != nil {
+		t.Fatalf("Syntax errors should be recovered from. Got %v", err)
+	}
+	switch typ := tok.tokens[3].(type) {
+	case ErrorToken:
+		if typ.Pos() != (Position{Offset: 11, Line: 2, Column: 8}) || typ.Original() != "unterminated string" {
+			t.Errorf("Wrong error. Got %v at %v want %v at %v", typ.Original(), typ.Pos(), "unterminated string", "2:8")
+		}
+	default:
+		t.Errorf("Got wrong token type at index %v. Got %v want %v", 3, typ, "ErrorToken")
+	}
+	if _, ok := tok.tokens[4].(CloseBlock); !ok {
+		t.Errorf("Expected the doc to close after the error. Got %v", tok.tokens[4])
+	}
+	if len(tok.Errors()) != 1 {
+		t.Errorf("Wrong number of errors. Got %v want %v", len(tok.Errors()), 1)
+	}
+}
+
+const recoveringDoc = `/* a plain comment mentioning /** and ; */
+/**
+@title "Broken
+@param since date "Since" "Since when"
+@table {
+	@column name "Name" "Name"
+*/
+SELECT 1;
+/*X*/
+/**
+@name
+@title "Fine"
+*/
+SELECT 2;`
+
+func TestRecovery(t *testing.T) {
+	tok := NewTokenizer(strings.NewReader(recoveringDoc))
+	err := tok.Tokenize()
+	if err != nil {
+		t.Fatalf("Syntax errors should be recovered from. Got %v", err)
+	}
+	want := []string{"/**", "/**", "title", "unterminated string", "param", "since", "date", "Since", "Since when",
+		"table", "column", "name", "Name", "Name", "unterminated {", "*/", ";",
+		"/**", "/**", "name", "expected a word", "title", "Fine", "*/", ";"}
+	if len(tok.tokens) != len(want) {
+		t.Fatalf("Document has incorrect number of tokens. Got %v want %v", len(tok.tokens), len(want))
+	}
+	for i, tok := range tok.tokens {
+		if tok.Original() != want[i] {
+			t.Errorf("Wrong token at index %v. Got '%v' want '%v'", i, tok.Original(), want[i])
+		}
+	}
+	for _, i := range []int{3, 14, 20} {
+		if _, ok := tok.tokens[i].(ErrorToken); !ok {
+			t.Errorf("Got wrong token type at index %v. Got %v want %v", i, tok.tokens[i], "ErrorToken")
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	src := `SELECT 1;
+/**
+@title "Two"
+*/
+SELECT ';' /* plain */ FROM two;
+;
+SELECT 3`
+	statements, err := Statements(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SELECT 1", "SELECT ';' /* plain */ FROM two", "SELECT 3"}
+	if len(statements) != len(want) {
+		t.Fatalf("Wrong statements. Got %+v", statements)
+	}
+	for i, s := range statements {
+		if s.SQL != want[i] {
+			t.Errorf("Wrong statement %d. Got %q want %q", i, s.SQL, want[i])
+		}
+	}
+	if statements[1].Pos.Line != 5 {
+		t.Errorf("Wrong start. Got %v want line 5", statements[1].Pos)
+	}
+	tok := NewTokenizer(strings.NewReader(src))
+	tok.Tokenize()
+	for _, token := range tok.Tokens() {
+		if end, ok := token.(CloseDoc); ok && end.Pos() != statements[1].End {
+			t.Errorf("Wrong end. Got %v want the CloseDoc's %v", statements[1].End, end.Pos())
+		}
+	}
+}
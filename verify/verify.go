@@ -0,0 +1,168 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/runner"
+)
+
+type Kind string
+
+const (
+	// Missing columns are documented but not returned.
+	Missing Kind = "missing"
+	// Extra columns are returned but not documented.
+	Extra Kind = "extra"
+	// Mismatched columns are returned with a type other than documented.
+	Mismatched Kind = "type"
+)
+
+// Mismatch is a difference between a query's documented and actual
+// result columns.
+type Mismatch struct {
+	Query        string        `json:"query"`
+	Column       string        `json:"column"`
+	Kind         Kind          `json:"kind"`
+	Documented   compiler.Type `json:"documented,omitempty"`
+	DatabaseType string        `json:"databaseType,omitempty"`
+}
+
+func (m Mismatch) String() string {
+	switch m.Kind {
+	case Missing:
+		return fmt.Sprintf("%s: column %s is documented but not returned", m.Query, m.Column)
+	case Extra:
+		return fmt.Sprintf("%s: column %s is returned but not documented", m.Query, m.Column)
+	}
+	return fmt.Sprintf("%s: column %s is documented as %s but the database returns %s", m.Query, m.Column, m.Documented, m.DatabaseType)
+}
+
+// Actual is a column as returned by the database.
+type Actual struct {
+	Name string
+	// DatabaseType is the driver's type name, empty if it doesn't know,
+	// as SQLite doesn't for expressions.
+	DatabaseType string
+}
+
+// Compare checks actual columns against q's output table. Columns whose
+// database type is unknown, or doesn't map to a documented type, are only
+// checked by name.
+func Compare(q compiler.QueryDoc, actual []Actual) []Mismatch {
+	mismatches := make([]Mismatch, 0)
+	returned := make(map[string]Actual)
+	for _, a := range actual {
+		returned[a.Name] = a
+	}
+	documented := make(map[string]bool)
+	for _, c := range q.Output.Columns {
+		documented[c.ProperName] = true
+		a, ok := returned[c.ProperName]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Query: q.Name, Column: c.ProperName, Kind: Missing, Documented: c.Type})
+			continue
+		}
+		typ, known := DatabaseType(a.DatabaseType)
+		if known && !compatible(c.Type, typ) {
+			mismatches = append(mismatches, Mismatch{
+				Query:        q.Name,
+				Column:       c.ProperName,
+				Kind:         Mismatched,
+				Documented:   c.Type,
+				DatabaseType: a.DatabaseType,
+			})
+		}
+	}
+	for _, a := range actual {
+		if !documented[a.Name] {
+			mismatches = append(mismatches, Mismatch{Query: q.Name, Column: a.Name, Kind: Extra, DatabaseType: a.DatabaseType})
+		}
+	}
+	return mismatches
+}
+
+// DatabaseType maps a driver's type name, such as VARCHAR(255), to a
+// compiler type.
+func DatabaseType(name string) (compiler.Type, bool) {
+	name = strings.TrimSpace(name)
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	if fields := strings.Fields(name); len(fields) > 0 {
+		name = fields[0]
+	}
+	typ, err := compiler.ParseType(name)
+	return typ, err == nil
+}
+
+// compatible allows integers where numbers are documented, and strings
+// where dates are, since SQLite stores dates as text.
+func compatible(documented, actual compiler.Type) bool {
+	switch {
+	case documented == actual:
+		return true
+	case documented == compiler.Number && actual == compiler.Integer:
+		return true
+	case (documented == compiler.Date || documented == compiler.DateTime) && actual == compiler.String:
+		return true
+	case documented == compiler.Date && actual == compiler.DateTime:
+		return true
+	}
+	return false
+}
+
+// Query runs q with values and compares the columns it returns. Values
+// missing for required params are filled in by SampleValues.
+func Query(ctx context.Context, r *runner.Runner, q compiler.QueryDoc, values map[string]interface{}) ([]Mismatch, error) {
+	merged := SampleValues(q)
+	for k, v := range values {
+		merged[k] = v
+	}
+	actual := make([]Actual, 0)
+	err := r.Query(ctx, q, merged, func(rows *runner.Rows) error {
+		types, err := rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		for _, t := range types {
+			actual = append(actual, Actual{Name: t.Name(), DatabaseType: t.DatabaseTypeName()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Compare(q, actual), nil
+}
+
+// SampleValues picks a valid value for every required param without a
+// default, so a query can be run just to see what it returns.
+func SampleValues(q compiler.QueryDoc) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, p := range q.Params {
+		if !p.Required || p.Default != nil {
+			continue
+		}
+		if len(p.Enum) > 0 {
+			values[p.ProperName] = p.Enum[0]
+			continue
+		}
+		switch p.Type {
+		case compiler.Integer, compiler.Number:
+			values[p.ProperName] = "0"
+		case compiler.Boolean:
+			values[p.ProperName] = "false"
+		case compiler.Date:
+			values[p.ProperName] = "1970-01-01"
+		case compiler.DateTime:
+			values[p.ProperName] = time.Unix(0, 0).UTC().Format(time.RFC3339)
+		default:
+			values[p.ProperName] = ""
+		}
+	}
+	return values
+}
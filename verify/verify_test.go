@@ -0,0 +1,103 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/runner"
+	_ "modernc.org/sqlite"
+)
+
+var users = compiler.QueryDoc{
+	Name: "users",
+	Output: compiler.Table{Columns: []compiler.Column{
+		{ProperName: "id", Type: compiler.Integer},
+		{ProperName: "name", Type: compiler.String},
+		{ProperName: "score", Type: compiler.Number},
+		{ProperName: "joined", Type: compiler.Date},
+		{ProperName: "email", Type: compiler.String},
+	}},
+}
+
+func TestCompare(t *testing.T) {
+	got := Compare(users, []Actual{
+		{Name: "id", DatabaseType: "TEXT"},
+		{Name: "name", DatabaseType: "VARCHAR(255)"},
+		{Name: "score", DatabaseType: "INTEGER"},
+		{Name: "joined", DatabaseType: "TEXT"},
+		{Name: "upper(name)", DatabaseType: ""},
+	})
+	want := []Mismatch{
+		{Query: "users", Column: "id", Kind: Mismatched, Documented: compiler.Integer, DatabaseType: "TEXT"},
+		{Query: "users", Column: "email", Kind: Missing, Documented: compiler.String},
+		{Query: "users", Column: "upper(name)", Kind: Extra},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Wrong mismatches. Got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Wrong mismatch at %v. Got %v want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSampleValues(t *testing.T) {
+	q := compiler.QueryDoc{Params: []compiler.Param{
+		{ProperName: "status", Required: true, Enum: []string{"open"}},
+		{ProperName: "since", Type: compiler.Date, Required: true},
+		{ProperName: "limit", Type: compiler.Integer},
+	}}
+	values := SampleValues(q)
+	if values["status"] != "open" || values["since"] != "1970-01-01" {
+		t.Errorf("Wrong sample values. Got %v", values)
+	}
+	if _, ok := values["limit"]; ok {
+		t.Errorf("Optional params shouldn't get a sample value. Got %v", values["limit"])
+	}
+}
+
+func TestQuery(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own.
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE users (id INTEGER, name TEXT, joined TEXT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := compiler.QueryDoc{
+		Name:   "users",
+		SQL:    "SELECT id, name, joined, upper(name) AS shout FROM users WHERE id > ${after}",
+		Params: []compiler.Param{{ProperName: "after", Type: compiler.Integer, Required: true}},
+		Output: compiler.Table{Columns: []compiler.Column{
+			{ProperName: "id", Type: compiler.String},
+			{ProperName: "name", Type: compiler.String},
+			{ProperName: "email", Type: compiler.String},
+		}},
+	}
+	// after is filled in by SampleValues.
+	got, err := Query(context.Background(), runner.New(db, "sqlite"), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mismatch{
+		{Query: "users", Column: "id", Kind: Mismatched, Documented: compiler.String, DatabaseType: "INTEGER"},
+		{Query: "users", Column: "email", Kind: Missing, Documented: compiler.String},
+		{Query: "users", Column: "joined", Kind: Extra, DatabaseType: "TEXT"},
+		{Query: "users", Column: "shout", Kind: Extra},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Wrong mismatches. Got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Wrong mismatch at %v. Got %v want %v", i, got[i], want[i])
+		}
+	}
+}
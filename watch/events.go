@@ -0,0 +1,65 @@
+package watch
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ReloadScript reloads a page whenever /events sends a reload.
+const ReloadScript = `<script>new EventSource("/events").addEventListener("reload", () => location.reload())</script>
+`
+
+// Broker fans events out to every browser listening with server-sent
+// events.
+type Broker struct {
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{clients: make(map[chan string]struct{})}
+}
+
+// Publish sends event to every client, dropping it for clients that are
+// still busy with an earlier one.
+func (b *Broker) Publish(event string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		select {
+		case c <- event:
+		default:
+		}
+	}
+}
+
+func (b *Broker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	c := make(chan string, 1)
+	b.mu.Lock()
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case event := <-c:
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", event)
+			flusher.Flush()
+		}
+	}
+}
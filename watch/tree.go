@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/christopher-henderson/DocStringParser/catalog"
+	"github.com/christopher-henderson/DocStringParser/compiler"
+	"github.com/christopher-henderson/DocStringParser/project"
+	"github.com/christopher-henderson/DocStringParser/sidecar"
+)
+
+// Tree keeps a catalog in step with the .sql files under some paths and
+// their sidecars, recompiling only the files that change.
+type Tree struct {
+	paths []string
+
+	mu      sync.RWMutex
+	files   map[string]file
+	catalog *catalog.Catalog
+}
+
+type file struct {
+	stamp
+	// sidecar is the stamp of the file's sidecar, zero without one.
+	sidecar stamp
+	docs    []compiler.QueryDoc
+}
+
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(info os.FileInfo) stamp {
+	return stamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (s stamp) same(other stamp) bool {
+	return s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+// New compiles every file under paths.
+func New(paths ...string) (*Tree, error) {
+	t := &Tree{paths: paths, files: make(map[string]file), catalog: catalog.New(nil)}
+	_, err := t.Poll()
+	return t, err
+}
+
+// Catalog is the latest catalog that compiled.
+func (t *Tree) Catalog() *catalog.Catalog {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.catalog
+}
+
+// Poll rescans the paths, recompiling new and modified files and dropping
+// deleted ones. It reports whether the catalog changed. A file that fails
+// to compile keeps its previous docs and is reported in err.
+func (t *Tree) Poll() (changed bool, err error) {
+	names := make([]string, 0)
+	for _, path := range t.paths {
+		found, err := catalog.SQLFiles(path)
+		if err != nil {
+			return false, err
+		}
+		names = append(names, found...)
+	}
+	t.mu.RLock()
+	files := make(map[string]file, len(names))
+	for _, name := range names {
+		files[name] = t.files[name]
+	}
+	changed = len(t.files) != len(files)
+	t.mu.RUnlock()
+	var failed error
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			return false, err
+		}
+		var side stamp
+		if info, err := os.Stat(sidecar.Path(name)); err == nil {
+			side = stampOf(info)
+		}
+		old := files[name]
+		if stampOf(info).same(old.stamp) && side.same(old.sidecar) {
+			continue
+		}
+		docs, err := project.New(project.Options{Workers: 1}).CompileFile(name)
+		if err != nil {
+			if failed == nil {
+				failed = fmt.Errorf("%s: %v", name, err)
+			}
+			docs = old.docs
+		} else {
+			changed = true
+		}
+		files[name] = file{stamp: stampOf(info), sidecar: side, docs: docs}
+	}
+	// The new stamps are kept whatever happens next, so a file that failed
+	// isn't recompiled, and its failure reported again, until it changes.
+	if !changed {
+		t.save(files, nil)
+		return false, failed
+	}
+	c := catalog.New(nil)
+	for _, name := range names {
+		c.Add(name, files[name].docs)
+	}
+	if err := c.Resolve(); err != nil {
+		t.save(files, nil)
+		return false, err
+	}
+	if err := c.CheckNames(); err != nil {
+		t.save(files, nil)
+		return false, err
+	}
+	t.save(files, c)
+	return true, failed
+}
+
+// save records files, and c unless it's nil.
+func (t *Tree) save(files map[string]file, c *catalog.Catalog) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files = files
+	if c != nil {
+		t.catalog = c
+	}
+}
+
+// Watch polls every interval until ctx is done, calling changed with the
+// new catalog after every change and reporting failures to errs.
+func (t *Tree) Watch(ctx context.Context, interval time.Duration, changed func(*catalog.Catalog), errs func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := t.Poll()
+			if err != nil {
+				errs(err)
+			}
+			if ok {
+				changed(t.Catalog())
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func write(t *testing.T, path, src string, mod time.Time) {
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, mod, mod)
+}
+
+func TestPoll(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.sql"), filepath.Join(dir, "b.sql")
+	then := time.Now().Add(-time.Hour)
+	write(t, a, "/**\n@title \"A\"\n*/\nSELECT 1;", then)
+	write(t, b, "/**\n@title \"B\"\n*/\nSELECT 2;", then)
+	tree, err := New(dir)
+	if err != nil {
+		t.Fatalf("Unexpected err %v", err)
+	}
+	if changed, err := tree.Poll(); changed || err != nil {
+		t.Errorf("Nothing should have changed. Got %v, %v", changed, err)
+	}
+	write(t, a, "/**\n@title \"A2\"\n*/\nSELECT 1;", time.Now())
+	changed, err := tree.Poll()
+	if !changed || err != nil || tree.Catalog().Docs[0].Title != "A2" {
+		t.Errorf("Expected A to be recompiled. Got %v, %v, %v", changed, err, tree.Catalog().Docs)
+	}
+	write(t, a, "/**\n@title\n*/\nSELECT 1;", time.Now().Add(time.Minute))
+	changed, err = tree.Poll()
+	if changed || err == nil || tree.Catalog().Docs[0].Title != "A2" {
+		t.Errorf("A broken file should keep its docs. Got %v, %v, %v", changed, err, tree.Catalog().Docs)
+	}
+	if changed, err := tree.Poll(); changed || err != nil {
+		t.Errorf("A broken file shouldn't be recompiled until it changes. Got %v, %v", changed, err)
+	}
+	write(t, a, "/**\n@name b\n*/\nSELECT 1;", time.Now().Add(2*time.Minute))
+	write(t, b, "/**\n@name b\n*/\nSELECT 2;", time.Now().Add(2*time.Minute))
+	if changed, err := tree.Poll(); changed || err == nil {
+		t.Errorf("Expected a duplicate name. Got %v, %v", changed, err)
+	}
+	if changed, err := tree.Poll(); changed || err != nil {
+		t.Errorf("The duplicate name shouldn't be reported again. Got %v, %v", changed, err)
+	}
+	os.Remove(b)
+	changed, _ = tree.Poll()
+	if !changed || len(tree.Catalog().Docs) != 1 {
+		t.Errorf("Expected B to be dropped. Got %v, %v", changed, tree.Catalog().Docs)
+	}
+}